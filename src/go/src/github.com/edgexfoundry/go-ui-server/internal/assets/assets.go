@@ -0,0 +1,29 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package assets holds the web client built into the binary. The build
+// copies the compiled client, resources/public, into the public directory
+// before compiling the server.
+package assets
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed public
+var files embed.FS
+
+// FS returns the embedded web client, nil if the binary was built without
+// one.
+func FS() fs.FS {
+	public, err := fs.Sub(files, "public")
+	if err != nil {
+		return nil
+	}
+	if _, err := fs.Stat(public, "index.html"); err != nil {
+		return nil
+	}
+	return public
+}
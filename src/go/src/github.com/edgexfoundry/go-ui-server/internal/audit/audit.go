@@ -0,0 +1,234 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/russolsen/transit"
+)
+
+// Masked replaces the value of secret arguments in the log.
+const Masked = "********"
+
+// secretArgs are the mutation arguments that are never written to the log
+var secretArgs = map[string]bool{
+	"password":           true,
+	"oldpw":              true,
+	"newpw":              true,
+	"token":              true,
+	"key":                true,
+	"cert":               true,
+	"encryptionKey":      true,
+	"initializingVector": true,
+}
+
+// Entry records one mutation.
+type Entry struct {
+	// Id is the position of the entry in the log, set when reading it
+	Id       int                    `json:"-"`
+	Time     time.Time              `json:"time"`
+	User     string                 `json:"user,omitempty"`
+	Session  string                 `json:"session,omitempty"`
+	Client   string                 `json:"client,omitempty"`
+	Mutation string                 `json:"mutation"`
+	Args     map[string]interface{} `json:"args,omitempty"`
+	// Status is the HTTP status of the last EdgeX service call, 0 if none
+	// was made
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Filter selects entries from the log. Empty fields match everything.
+type Filter struct {
+	User     string
+	Mutation string
+	From     time.Time
+	To       time.Time
+}
+
+func (f Filter) matches(entry *Entry) bool {
+	if f.User != "" && entry.User != f.User {
+		return false
+	}
+	if f.Mutation != "" && !strings.Contains(entry.Mutation, f.Mutation) {
+		return false
+	}
+	if !f.From.IsZero() && entry.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && entry.Time.After(f.To) {
+		return false
+	}
+	return true
+}
+
+// Log is an append-only file of JSON lines.
+type Log struct {
+	mutex sync.Mutex
+	path  string
+	file  *os.File
+}
+
+// Trail is the log used by the server. Nothing is recorded until it is
+// opened.
+var Trail = &Log{}
+
+// Open appends entries to the file at path.
+func Open(path string) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open audit log (%s): %v", path, err)
+	}
+	return &Log{path: path, file: file}, nil
+}
+
+// Record appends entry to the log.
+func (l *Log) Record(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	_, err = l.file.Write(append(line, '\n'))
+	return err
+}
+
+// Query returns the total number of entries matching filter and up to limit
+// of them, newest first, skipping the first offset. The file is read as a
+// stream keeping only the last offset+limit matches, and entries recorded
+// meanwhile are not waited for.
+func (l *Log) Query(filter Filter, offset int, limit int) ([]Entry, int, error) {
+	l.mutex.Lock()
+	path, file := l.path, l.file
+	var size int64
+	if file != nil {
+		info, err := file.Stat()
+		if err != nil {
+			l.mutex.Unlock()
+			return nil, 0, err
+		}
+		size = info.Size()
+	}
+	l.mutex.Unlock()
+	if path == "" {
+		return []Entry{}, 0, nil
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if limit < 0 {
+		limit = 0
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer in.Close()
+	// match i of the last keep matches is held in ring[i%keep]
+	keep := offset + limit
+	if keep < 0 {
+		return nil, 0, fmt.Errorf("offset %d and limit %d out of range", offset, limit)
+	}
+	ring := make([]Entry, 0, 1024)
+	total := 0
+	scanner := bufio.NewScanner(io.LimitReader(in, size))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	id := 0
+	for scanner.Scan() {
+		id++
+		var entry Entry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+		entry.Id = id
+		if !filter.matches(&entry) {
+			continue
+		}
+		if len(ring) < keep {
+			ring = append(ring, entry)
+		} else if keep > 0 {
+			ring[total%keep] = entry
+		}
+		total++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+	n := len(ring) - offset
+	if n > limit {
+		n = limit
+	}
+	if n < 0 {
+		n = 0
+	}
+	result := make([]Entry, 0, n)
+	for i := total - 1 - offset; i >= total-len(ring) && len(result) < limit; i-- {
+		result = append(result, ring[i%keep])
+	}
+	return result, total, nil
+}
+
+// Sanitize converts the transit arguments of a mutation to plain JSON
+// values, masking secrets.
+func Sanitize(args map[interface{}]interface{}) map[string]interface{} {
+	if args == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		key := fmt.Sprintf("%v", plain(k))
+		if secretArgs[key] {
+			if v != nil && v != "" {
+				result[key] = Masked
+			}
+			continue
+		}
+		result[key] = plain(v)
+	}
+	return result
+}
+
+func plain(v interface{}) interface{} {
+	switch t := v.(type) {
+	case transit.Keyword:
+		return string(t)
+	case transit.Symbol:
+		return string(t)
+	case transit.TaggedValue:
+		return fmt.Sprintf("%v", plain(t.Value))
+	case map[interface{}]interface{}:
+		return Sanitize(t)
+	case []interface{}:
+		result := make([]interface{}, len(t))
+		for i, e := range t {
+			result[i] = plain(e)
+		}
+		return result
+	case *list.List:
+		result := make([]interface{}, 0, t.Len())
+		for e := t.Front(); e != nil; e = e.Next() {
+			result = append(result, plain(e.Value))
+		}
+		return result
+	case string, bool, nil, int, int64, float64:
+		return t
+	default:
+		return fmt.Sprintf("%v", t)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testLog returns a log of ten entries, recorded a minute apart by alice
+// and bob in turn, with a line that is not an entry after the fifth.
+func testLog(t *testing.T) (*Log, time.Time) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.file.Close() })
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		user := "alice"
+		if i%2 == 1 {
+			user = "bob"
+		}
+		if err := l.Record(Entry{Time: start.Add(time.Duration(i) * time.Minute), User: user, Mutation: "m/add-device"}); err != nil {
+			t.Fatal(err)
+		}
+		if i == 4 {
+			l.file.WriteString("not json\n")
+		}
+	}
+	return l, start
+}
+
+func ids(entries []Entry) []int {
+	result := make([]int, len(entries))
+	for i, entry := range entries {
+		result[i] = entry.Id
+	}
+	return result
+}
+
+func TestQuery(t *testing.T) {
+	l, start := testLog(t)
+	tests := []struct {
+		name   string
+		filter Filter
+		offset int
+		limit  int
+		ids    []int
+		total  int
+	}{
+		{"first page", Filter{}, 0, 3, []int{11, 10, 9}, 10},
+		{"second page", Filter{}, 3, 3, []int{8, 7, 5}, 10},
+		{"across the bad line", Filter{}, 4, 3, []int{7, 5, 4}, 10},
+		{"last page cut short", Filter{}, 8, 5, []int{2, 1}, 10},
+		{"past the end", Filter{}, 10, 5, []int{}, 10},
+		{"all", Filter{}, 0, 100, []int{11, 10, 9, 8, 7, 5, 4, 3, 2, 1}, 10},
+		{"no limit", Filter{}, 0, 0, []int{}, 10},
+		{"user", Filter{User: "bob"}, 1, 2, []int{9, 7}, 5},
+		{"mutation", Filter{Mutation: "delete"}, 0, 10, []int{}, 0},
+		{"time range", Filter{From: start.Add(2 * time.Minute), To: start.Add(4 * time.Minute)}, 0, 10, []int{5, 4, 3}, 3},
+		{"negative offset", Filter{}, -5, 2, []int{11, 10}, 10},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, total, err := l.Query(test.filter, test.offset, test.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ids(entries)
+			if total != test.total || len(got) != len(test.ids) {
+				t.Fatalf("entries %v of %d, want %v of %d", got, total, test.ids, test.total)
+			}
+			for i := range got {
+				if got[i] != test.ids[i] {
+					t.Fatalf("entries %v, want %v", got, test.ids)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryOutOfRange(t *testing.T) {
+	l, _ := testLog(t)
+	if _, _, err := l.Query(Filter{}, math.MaxInt64, math.MaxInt64); err == nil {
+		t.Error("offset and limit overflowing accepted")
+	}
+	entries, total, err := l.Query(Filter{}, 0, math.MaxInt64)
+	if err != nil || total != 10 || len(entries) != 10 {
+		t.Errorf("%d entries of %d, %v, want all 10", len(entries), total, err)
+	}
+}
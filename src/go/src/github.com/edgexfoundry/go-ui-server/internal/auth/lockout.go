@@ -0,0 +1,248 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package auth
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LockoutConfig sets how failed logins are throttled. After each failure a
+// client has to wait twice as long as before, starting at BackoffBase, until
+// MaxFailures is reached and the client is locked out. Failures from all
+// clients together within GlobalWindow are limited by GlobalMaxFailures;
+// beyond it, clients with recent failures are locked out for LockoutDuration,
+// while clients without any may still log in, so that anonymous guessing
+// cannot lock out every user.
+type LockoutConfig struct {
+	MaxFailures       int
+	LockoutDuration   time.Duration
+	BackoffBase       time.Duration
+	BackoffMax        time.Duration
+	GlobalMaxFailures int
+	GlobalWindow      time.Duration
+}
+
+var DefaultLockoutConfig = LockoutConfig{
+	MaxFailures:       5,
+	LockoutDuration:   15 * time.Minute,
+	BackoffBase:       time.Second,
+	BackoffMax:        time.Minute,
+	GlobalMaxFailures: 50,
+	GlobalWindow:      5 * time.Minute,
+}
+
+// GlobalClient is the client name under which the global lockout is listed.
+const GlobalClient = "*"
+
+// attemptTimeout bounds how long a login attempt holds the slot of its
+// client, should it never report how it ended
+const attemptTimeout = 30 * time.Second
+
+// LockoutError is returned while a client must not try to log in.
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("Too many failed logins, try again in %d seconds", int(e.RetryAfter.Seconds()+0.5))
+}
+
+// ClientState is the failed login record of a client.
+type ClientState struct {
+	Client      string
+	Failures    int
+	LastFailure time.Time
+	Until       time.Time
+	Locked      bool
+}
+
+// LoginGuard tracks failed logins per client and in total.
+type LoginGuard struct {
+	mutex   sync.Mutex
+	config  LockoutConfig
+	clients map[string]*ClientState
+	global  []time.Time
+	// globalUntil is the end of the global lockout
+	globalUntil time.Time
+	// pending holds the clients with a login attempt in progress and until
+	// when their slot is held
+	pending map[string]time.Time
+	// now is the clock, replaced in tests
+	now func() time.Time
+}
+
+// Logins is the guard used by the server.
+var Logins = NewLoginGuard(DefaultLockoutConfig)
+
+func NewLoginGuard(config LockoutConfig) *LoginGuard {
+	return &LoginGuard{
+		config:  config.withDefaults(),
+		clients: make(map[string]*ClientState),
+		pending: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// Configure changes the throttling of failed logins, keeping the failures
+// counted so far.
+func (g *LoginGuard) Configure(config LockoutConfig) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.config = config.withDefaults()
+}
+
+// withDefaults returns config with the default of each setting not set.
+func (config LockoutConfig) withDefaults() LockoutConfig {
+	if config.MaxFailures <= 0 {
+		config.MaxFailures = DefaultLockoutConfig.MaxFailures
+	}
+	if config.LockoutDuration <= 0 {
+		config.LockoutDuration = DefaultLockoutConfig.LockoutDuration
+	}
+	if config.BackoffBase <= 0 {
+		config.BackoffBase = DefaultLockoutConfig.BackoffBase
+	}
+	if config.BackoffMax <= 0 {
+		config.BackoffMax = DefaultLockoutConfig.BackoffMax
+	}
+	if config.GlobalMaxFailures <= 0 {
+		config.GlobalMaxFailures = DefaultLockoutConfig.GlobalMaxFailures
+	}
+	if config.GlobalWindow <= 0 {
+		config.GlobalWindow = DefaultLockoutConfig.GlobalWindow
+	}
+	return config
+}
+
+// expire forgets clients whose last failure is older than the lockout
+// duration, global failures outside the window and attempts that timed out.
+func (g *LoginGuard) expire(now time.Time) {
+	for client, state := range g.clients {
+		if now.After(state.Until) && now.Sub(state.LastFailure) > g.config.LockoutDuration {
+			delete(g.clients, client)
+		}
+	}
+	i := 0
+	for i < len(g.global) && now.Sub(g.global[i]) > g.config.GlobalWindow {
+		i++
+	}
+	g.global = g.global[i:]
+	for client, until := range g.pending {
+		if !now.Before(until) {
+			delete(g.pending, client)
+		}
+	}
+}
+
+// Check returns a LockoutError if client may not try to log in now, and
+// otherwise holds the slot of client until the attempt ends with Failed,
+// Succeeded or Done. A client has one attempt at a time, so that concurrent
+// guesses cannot all pass before the first failure is recorded.
+func (g *LoginGuard) Check(client string) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	now := g.now()
+	g.expire(now)
+	state, failed := g.clients[client]
+	if failed && now.Before(g.globalUntil) {
+		return &LockoutError{RetryAfter: g.globalUntil.Sub(now)}
+	}
+	if failed && now.Before(state.Until) {
+		return &LockoutError{RetryAfter: state.Until.Sub(now)}
+	}
+	if _, ok := g.pending[client]; ok {
+		return &LockoutError{RetryAfter: g.config.BackoffBase}
+	}
+	g.pending[client] = now.Add(attemptTimeout)
+	return nil
+}
+
+// Done ends the attempt of client without a verdict on its credentials.
+func (g *LoginGuard) Done(client string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	delete(g.pending, client)
+}
+
+// Failed records a failed login of client.
+func (g *LoginGuard) Failed(client string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	now := g.now()
+	g.expire(now)
+	delete(g.pending, client)
+	state, ok := g.clients[client]
+	if !ok {
+		state = &ClientState{Client: client}
+		g.clients[client] = state
+	}
+	state.Failures++
+	state.LastFailure = now
+	if state.Failures >= g.config.MaxFailures {
+		state.Locked = true
+		state.Until = now.Add(g.config.LockoutDuration)
+	} else {
+		delay := g.config.BackoffBase << uint(state.Failures-1)
+		if delay > g.config.BackoffMax || delay <= 0 {
+			delay = g.config.BackoffMax
+		}
+		state.Until = now.Add(delay)
+	}
+	g.global = append(g.global, now)
+	if len(g.global) >= g.config.GlobalMaxFailures {
+		g.globalUntil = now.Add(g.config.LockoutDuration)
+		g.global = nil
+	}
+}
+
+// Succeeded clears the failed logins of client.
+func (g *LoginGuard) Succeeded(client string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	delete(g.clients, client)
+	delete(g.pending, client)
+}
+
+// List returns the clients with recent failed logins, including the global
+// lockout while it lasts.
+func (g *LoginGuard) List() []ClientState {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	now := g.now()
+	g.expire(now)
+	result := make([]ClientState, 0, len(g.clients)+1)
+	if now.Before(g.globalUntil) {
+		result = append(result, ClientState{Client: GlobalClient, Failures: g.config.GlobalMaxFailures,
+			Until: g.globalUntil, Locked: true})
+	}
+	for _, state := range g.clients {
+		result = append(result, *state)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Client < result[j].Client
+	})
+	return result
+}
+
+// Clear forgets the failed logins of client, or of all clients and the
+// global lockout if client is empty.
+func (g *LoginGuard) Clear(client string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	switch client {
+	case "":
+		g.clients = make(map[string]*ClientState)
+		g.global = nil
+		g.globalUntil = time.Time{}
+	case GlobalClient:
+		g.global = nil
+		g.globalUntil = time.Time{}
+	default:
+		delete(g.clients, client)
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+var testLockoutConfig = LockoutConfig{
+	MaxFailures:       4,
+	LockoutDuration:   10 * time.Minute,
+	BackoffBase:       time.Second,
+	BackoffMax:        3 * time.Second,
+	GlobalMaxFailures: 3,
+	GlobalWindow:      time.Minute,
+}
+
+// testGuard returns a guard of config whose clock only moves when the
+// returned function is called.
+func testGuard(config LockoutConfig) (*LoginGuard, func(time.Duration)) {
+	g := NewLoginGuard(config)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return now }
+	return g, func(d time.Duration) { now = now.Add(d) }
+}
+
+// retryAfter returns how long client has to wait, 0 if it may log in. The
+// attempt let through is ended without a verdict.
+func retryAfter(t *testing.T, g *LoginGuard, client string) time.Duration {
+	t.Helper()
+	err := g.Check(client)
+	if err == nil {
+		g.Done(client)
+		return 0
+	}
+	lockout, ok := err.(*LockoutError)
+	if !ok {
+		t.Fatalf("Check: %v, want a LockoutError", err)
+	}
+	return lockout.RetryAfter
+}
+
+// fail records n failed logins of client, each when it may try again.
+func fail(t *testing.T, g *LoginGuard, advance func(time.Duration), client string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		advance(retryAfter(t, g, client))
+		if err := g.Check(client); err != nil {
+			t.Fatalf("Check after waiting: %v", err)
+		}
+		g.Failed(client)
+	}
+}
+
+func TestLoginBackoff(t *testing.T) {
+	config := testLockoutConfig
+	config.GlobalMaxFailures = 100
+	tests := []struct {
+		failures int
+		wait     time.Duration
+	}{
+		{0, 0},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 3 * time.Second}, // 4s capped at BackoffMax
+		{4, 10 * time.Minute},
+	}
+	for _, test := range tests {
+		g, advance := testGuard(config)
+		fail(t, g, advance, "a", test.failures)
+		if wait := retryAfter(t, g, "a"); wait != test.wait {
+			t.Errorf("after %d failures wait %v, want %v", test.failures, wait, test.wait)
+		}
+		if test.wait > 0 {
+			advance(test.wait - time.Millisecond)
+			if wait := retryAfter(t, g, "a"); wait != time.Millisecond {
+				t.Errorf("after %d failures wait %v a millisecond early, want 1ms", test.failures, wait)
+			}
+			advance(time.Millisecond)
+			if wait := retryAfter(t, g, "a"); wait != 0 {
+				t.Errorf("after %d failures wait %v once the backoff is over", test.failures, wait)
+			}
+		}
+	}
+}
+
+func TestLoginLockoutExpiry(t *testing.T) {
+	config := testLockoutConfig
+	config.GlobalMaxFailures = 100
+	g, advance := testGuard(config)
+	fail(t, g, advance, "a", config.MaxFailures)
+	list := g.List()
+	if len(list) != 1 || list[0].Client != "a" || !list[0].Locked || list[0].Failures != config.MaxFailures {
+		t.Fatalf("lockouts %+v, want a locked after %d failures", list, config.MaxFailures)
+	}
+	if retryAfter(t, g, "b") != 0 {
+		t.Error("a client without failures locked out")
+	}
+
+	advance(config.LockoutDuration - time.Second)
+	if wait := retryAfter(t, g, "a"); wait != time.Second {
+		t.Errorf("wait %v before the lockout ends, want 1s", wait)
+	}
+	advance(time.Second)
+	if wait := retryAfter(t, g, "a"); wait != 0 {
+		t.Errorf("wait %v once the lockout is over", wait)
+	}
+	advance(time.Millisecond)
+	if list := g.List(); len(list) != 0 {
+		t.Errorf("lockouts %+v after the lockout, want none", list)
+	}
+	// the failures are forgotten, the next one starts the backoff again
+	fail(t, g, advance, "a", 1)
+	if wait := retryAfter(t, g, "a"); wait != config.BackoffBase {
+		t.Errorf("wait %v after a new failure, want %v", wait, config.BackoffBase)
+	}
+}
+
+func TestLoginGlobalLimit(t *testing.T) {
+	g, advance := testGuard(testLockoutConfig)
+	fail(t, g, advance, "a", 1)
+	fail(t, g, advance, "b", 1)
+	fail(t, g, advance, "c", 1)
+	advance(testLockoutConfig.BackoffMax)
+
+	tests := []struct {
+		client string
+		wait   time.Duration
+	}{
+		{"a", testLockoutConfig.LockoutDuration - testLockoutConfig.BackoffMax},
+		{"c", testLockoutConfig.LockoutDuration - testLockoutConfig.BackoffMax},
+		{"d", 0},
+	}
+	for _, test := range tests {
+		if wait := retryAfter(t, g, test.client); wait != test.wait {
+			t.Errorf("client %s waits %v past the global limit, want %v", test.client, wait, test.wait)
+		}
+	}
+	if list := g.List(); len(list) != 4 || list[0].Client != GlobalClient || !list[0].Locked {
+		t.Errorf("lockouts %+v, want the global lockout and a, b and c", list)
+	}
+
+	advance(testLockoutConfig.LockoutDuration)
+	if wait := retryAfter(t, g, "a"); wait != 0 {
+		t.Errorf("wait %v after the global lockout", wait)
+	}
+}
+
+func TestLoginGlobalWindow(t *testing.T) {
+	g, advance := testGuard(testLockoutConfig)
+	fail(t, g, advance, "a", 1)
+	fail(t, g, advance, "b", 1)
+	// the first failures are out of the window before the third one
+	advance(testLockoutConfig.GlobalWindow + time.Millisecond)
+	fail(t, g, advance, "c", 1)
+	advance(testLockoutConfig.BackoffMax)
+	if wait := retryAfter(t, g, "a"); wait != 0 {
+		t.Errorf("wait %v, want no global lockout for failures spread beyond the window", wait)
+	}
+}
+
+func TestLoginOneAttempt(t *testing.T) {
+	g, _ := testGuard(testLockoutConfig)
+	if err := g.Check("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Check("a"); err == nil {
+		t.Error("second attempt let through while the first is pending")
+	}
+	if err := g.Check("b"); err != nil {
+		t.Errorf("attempt of another client: %v", err)
+	}
+	g.Succeeded("a")
+	if err := g.Check("a"); err != nil {
+		t.Errorf("attempt after the first succeeded: %v", err)
+	}
+}
+
+func TestLoginClear(t *testing.T) {
+	// a is locked out on its own, b and c only by the global lockout once
+	// their backoff is over
+	tests := []struct {
+		clear  string
+		locked [3]bool
+		listed []string
+	}{
+		{"a", [3]bool{false, true, true}, []string{GlobalClient, "b", "c"}},
+		{GlobalClient, [3]bool{true, false, false}, []string{"a", "b", "c"}},
+		{"", [3]bool{false, false, false}, nil},
+	}
+	for _, test := range tests {
+		t.Run("clear "+test.clear, func(t *testing.T) {
+			config := testLockoutConfig
+			config.MaxFailures = 2
+			g, advance := testGuard(config)
+			fail(t, g, advance, "a", 2)
+			fail(t, g, advance, "b", 1)
+			fail(t, g, advance, "c", 1)
+			advance(config.BackoffMax)
+			g.Clear(test.clear)
+			for i, client := range []string{"a", "b", "c"} {
+				if locked := retryAfter(t, g, client) > 0; locked != test.locked[i] {
+					t.Errorf("%s locked out %v, want %v", client, locked, test.locked[i])
+				}
+			}
+			list := g.List()
+			if len(list) != len(test.listed) {
+				t.Fatalf("lockouts %+v, want %v", list, test.listed)
+			}
+			for i, client := range test.listed {
+				if list[i].Client != client {
+					t.Errorf("lockouts %+v, want %v", list, test.listed)
+				}
+			}
+		})
+	}
+}
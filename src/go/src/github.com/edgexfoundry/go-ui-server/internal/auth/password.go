@@ -0,0 +1,205 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+
+	argon2Prefix  = "$argon2id$"
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+// PasswordPolicy is the set of rules a new password must follow.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 8}
+
+// Check returns an error describing the first rule password breaks.
+func (p PasswordPolicy) Check(user string, password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("Password must be at least %d characters long", p.MinLength)
+	}
+	if password == user {
+		return fmt.Errorf("Password must not be the same as the user name")
+	}
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	switch {
+	case p.RequireUpper && !upper:
+		return fmt.Errorf("Password must contain an upper case letter")
+	case p.RequireLower && !lower:
+		return fmt.Errorf("Password must contain a lower case letter")
+	case p.RequireDigit && !digit:
+		return fmt.Errorf("Password must contain a digit")
+	case p.RequireSymbol && !symbol:
+		return fmt.Errorf("Password must contain a symbol")
+	}
+	return nil
+}
+
+// HashConfig selects the algorithm used for new password hashes. Hashes made
+// with any supported algorithm or cost still verify.
+type HashConfig struct {
+	// Algorithm is either bcrypt or argon2id
+	Algorithm  string
+	BcryptCost int
+	// Argon2Memory is in KiB
+	Argon2Memory  uint32
+	Argon2Time    uint32
+	Argon2Threads uint8
+}
+
+var DefaultHashConfig = HashConfig{
+	Algorithm:     AlgorithmBcrypt,
+	BcryptCost:    bcrypt.DefaultCost,
+	Argon2Memory:  64 * 1024,
+	Argon2Time:    3,
+	Argon2Threads: 2,
+}
+
+// withDefaults fills in the settings left out of the configuration.
+func (c HashConfig) withDefaults() HashConfig {
+	if c.Algorithm == "" {
+		c.Algorithm = DefaultHashConfig.Algorithm
+	}
+	if c.BcryptCost == 0 {
+		c.BcryptCost = DefaultHashConfig.BcryptCost
+	}
+	if c.Argon2Memory == 0 {
+		c.Argon2Memory = DefaultHashConfig.Argon2Memory
+	}
+	if c.Argon2Time == 0 {
+		c.Argon2Time = DefaultHashConfig.Argon2Time
+	}
+	if c.Argon2Threads == 0 {
+		c.Argon2Threads = DefaultHashConfig.Argon2Threads
+	}
+	return c
+}
+
+func (c HashConfig) Validate() error {
+	c = c.withDefaults()
+	switch c.Algorithm {
+	case AlgorithmBcrypt:
+		if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case AlgorithmArgon2id:
+	default:
+		return fmt.Errorf("unknown password hash algorithm %s", c.Algorithm)
+	}
+	return nil
+}
+
+// Hash returns the encoded hash of password.
+func (c HashConfig) Hash(password string) (string, error) {
+	c = c.withDefaults()
+	if c.Algorithm == AlgorithmArgon2id {
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, c.Argon2Time, c.Argon2Memory, c.Argon2Threads, argon2KeyLen)
+		return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version,
+			c.Argon2Memory, c.Argon2Time, c.Argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), c.BcryptCost)
+	return string(hashedBytes), err
+}
+
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func parseArgon2(hash string) (*argon2Params, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return nil, fmt.Errorf("invalid argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2id version")
+	}
+	p := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return nil, err
+	}
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, err
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// VerifyHash reports whether password matches hash.
+func VerifyHash(hash string, password string) bool {
+	if strings.HasPrefix(hash, argon2Prefix) {
+		p, err := parseArgon2(hash)
+		if err != nil {
+			return false
+		}
+		key := argon2.IDKey([]byte(password), p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+		return subtle.ConstantTimeCompare(key, p.key) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NeedsRehash reports whether hash was made with other settings than c, so
+// that it can be upgraded the next time the password is known.
+func (c HashConfig) NeedsRehash(hash string) bool {
+	c = c.withDefaults()
+	if strings.HasPrefix(hash, argon2Prefix) {
+		if c.Algorithm != AlgorithmArgon2id {
+			return true
+		}
+		p, err := parseArgon2(hash)
+		return err != nil || p.memory != c.Argon2Memory || p.time != c.Argon2Time || p.threads != c.Argon2Threads
+	}
+	if c.Algorithm != AlgorithmBcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != c.BcryptCost
+}
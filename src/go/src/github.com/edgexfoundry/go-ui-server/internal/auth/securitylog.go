@@ -0,0 +1,67 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Security events
+const (
+	EventLoginSucceeded     = "login-succeeded"
+	EventLoginFailed        = "login-failed"
+	EventLoginLockedOut     = "login-locked-out"
+	EventPasswordChanged    = "password-changed"
+	EventPasswordNotChanged = "password-change-failed"
+	EventLockoutCleared     = "lockout-cleared"
+)
+
+// SecurityLog writes security events as JSON lines.
+type SecurityLog struct {
+	mutex sync.Mutex
+	out   io.Writer
+}
+
+// Security is the log used by the server. It writes to stderr until a log
+// file is opened.
+var Security = &SecurityLog{out: os.Stderr}
+
+// OpenSecurityLog appends security events to the file at path.
+func OpenSecurityLog(path string) (*SecurityLog, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open security log (%s): %v", path, err)
+	}
+	return &SecurityLog{out: file}, nil
+}
+
+// Log records event for user from client.
+func (l *SecurityLog) Log(event string, user string, client string, detail string) {
+	entry := struct {
+		Time   string `json:"time"`
+		Event  string `json:"event"`
+		User   string `json:"user,omitempty"`
+		Client string `json:"client,omitempty"`
+		Detail string `json:"detail,omitempty"`
+	}{
+		Time:   time.Now().UTC().Format(time.RFC3339),
+		Event:  event,
+		User:   user,
+		Client: client,
+		Detail: detail,
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.out.Write(append(line, '\n'))
+}
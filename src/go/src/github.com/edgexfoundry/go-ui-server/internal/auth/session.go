@@ -0,0 +1,273 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// SessionCookie is the name of the cookie carrying the session token.
+	SessionCookie = "EDGEX_UI_SESSION"
+	// SessionHeader may be used instead of the cookie by non-browser clients.
+	SessionHeader = "X-Session-Id"
+
+	DefaultIdleTimeout     = 30 * time.Minute
+	DefaultAbsoluteTimeout = 8 * time.Hour
+)
+
+// Session is a logged in client of the UI server. The Token is the secret
+// presented by the client, the Handle identifies the session when it is
+// listed or revoked and is safe to show to the user. A Restricted session
+// may only change the user's password. Users logged in through an external
+// identity Provider have no account in the user store, their Role is taken
+// from the provider at login.
+type Session struct {
+	Token      string
+	Handle     string
+	User       string
+	Restricted bool
+	Provider   string
+	Role       Role
+	RemoteAddr string
+	UserAgent  string
+	Created    time.Time
+	LastSeen   time.Time
+}
+
+// SessionStore holds the server side sessions. A session expires when it has
+// not been used for the idle timeout, or once the absolute timeout has passed
+// since login, whichever comes first.
+type SessionStore struct {
+	mutex    sync.Mutex
+	sessions map[string]*Session
+	idle     time.Duration
+	absolute time.Duration
+}
+
+// Sessions is the store used by the server.
+var Sessions = NewSessionStore(DefaultIdleTimeout, DefaultAbsoluteTimeout)
+
+func NewSessionStore(idle time.Duration, absolute time.Duration) *SessionStore {
+	s := &SessionStore{sessions: make(map[string]*Session)}
+	s.Configure(idle, absolute)
+	return s
+}
+
+// Configure changes the timeouts, which apply to existing sessions too.
+func (s *SessionStore) Configure(idle time.Duration, absolute time.Duration) {
+	if idle <= 0 {
+		idle = DefaultIdleTimeout
+	}
+	if absolute <= 0 {
+		absolute = DefaultAbsoluteTimeout
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.idle = idle
+	s.absolute = absolute
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *SessionStore) expired(session *Session, now time.Time) bool {
+	return now.Sub(session.LastSeen) > s.idle || now.Sub(session.Created) > s.absolute
+}
+
+func (s *SessionStore) prune(now time.Time) {
+	for token, session := range s.sessions {
+		if s.expired(session, now) {
+			delete(s.sessions, token)
+		}
+	}
+}
+
+// Create starts a new session for user.
+func (s *SessionStore) Create(user string, restricted bool, r *http.Request) (*Session, error) {
+	return s.add(&Session{User: user, Restricted: restricted}, r)
+}
+
+// CreateExternal starts a new session for a user logged in through provider
+// with the given role.
+func (s *SessionStore) CreateExternal(user string, role Role, provider string, r *http.Request) (*Session, error) {
+	return s.add(&Session{User: user, Provider: provider, Role: role}, r)
+}
+
+func (s *SessionStore) add(session *Session, r *http.Request) (*Session, error) {
+	token, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	handle, err := randomString(8)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session.Token = token
+	session.Handle = handle
+	session.Created = now
+	session.LastSeen = now
+	if r != nil {
+		session.RemoteAddr = r.RemoteAddr
+		session.UserAgent = r.UserAgent()
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.prune(now)
+	s.sessions[token] = session
+	return session, nil
+}
+
+// Get returns the live session for token and marks it as used.
+func (s *SessionStore) Get(token string) (*Session, bool) {
+	if token == "" {
+		return nil, false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	session, ok := s.sessions[token]
+	if !ok {
+		return nil, false
+	}
+	now := time.Now()
+	if s.expired(session, now) {
+		delete(s.sessions, token)
+		return nil, false
+	}
+	session.LastSeen = now
+	result := *session
+	return &result, true
+}
+
+// Delete ends the session for token.
+func (s *SessionStore) Delete(token string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.sessions, token)
+}
+
+// List returns the live sessions of user logged in through provider, empty
+// for local users, oldest first.
+func (s *SessionStore) List(user string, provider string) []Session {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.prune(time.Now())
+	result := make([]Session, 0)
+	for _, session := range s.sessions {
+		if session.User == user && session.Provider == provider {
+			result = append(result, *session)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Created.Before(result[j].Created)
+	})
+	return result
+}
+
+// Revoke ends the session identified by handle of user logged in through
+// provider, empty for local users. It reports whether such a session
+// existed.
+func (s *SessionStore) Revoke(user string, provider string, handle string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for token, session := range s.sessions {
+		if session.User == user && session.Provider == provider && session.Handle == handle {
+			delete(s.sessions, token)
+			return true
+		}
+	}
+	return false
+}
+
+// RevokeUser ends all sessions of the local user.
+func (s *SessionStore) RevokeUser(user string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for token, session := range s.sessions {
+		if session.User == user && session.Provider == "" {
+			delete(s.sessions, token)
+		}
+	}
+}
+
+// Unrestrict lifts the restriction from the sessions of user once the
+// password has been changed.
+func (s *SessionStore) Unrestrict(user string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, session := range s.sessions {
+		if session.User == user && session.Provider == "" {
+			session.Restricted = false
+		}
+	}
+}
+
+// SessionToken extracts the session token from the request header or cookie.
+func SessionToken(r *http.Request) string {
+	if token := r.Header.Get(SessionHeader); token != "" {
+		return token
+	}
+	if cookie, err := r.Cookie(SessionCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// CookieOptions sets where the browser sends the session cookie.
+type CookieOptions struct {
+	// Path is the base path the UI is served under, "/" if not set
+	Path string
+	// Secure marks the cookie HTTPS only even for requests over plain HTTP,
+	// as they are from a reverse proxy terminating TLS
+	Secure bool
+}
+
+// IsSecure tells whether cookies sent in response to r are HTTPS only.
+func (o CookieOptions) IsSecure(r *http.Request) bool {
+	return o.Secure || r.TLS != nil
+}
+
+func (o CookieOptions) path() string {
+	if o.Path == "" {
+		return "/"
+	}
+	return o.Path
+}
+
+// SetSessionCookie delivers the session token to the browser as an HttpOnly cookie.
+func SetSessionCookie(w http.ResponseWriter, r *http.Request, session *Session, options CookieOptions) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    session.Token,
+		Path:     options.path(),
+		HttpOnly: true,
+		Secure:   options.IsSecure(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ClearSessionCookie removes the session cookie from the browser.
+func ClearSessionCookie(w http.ResponseWriter, r *http.Request, options CookieOptions) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    "",
+		Path:     options.path(),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   options.IsSecure(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,382 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Role is the level of access granted to a user. Each role includes the
+// rights of the roles below it.
+type Role int
+
+const (
+	// RoleNone is required by operations open to clients without a session.
+	RoleNone Role = iota
+	RoleViewer
+	RoleOperator
+	RoleAdmin
+)
+
+var roleNames = []string{"none", "viewer", "operator", "admin"}
+
+func (r Role) String() string {
+	if r < RoleNone || r > RoleAdmin {
+		return "unknown"
+	}
+	return roleNames[r]
+}
+
+func ParseRole(name string) (Role, error) {
+	for i, n := range roleNames {
+		if i != int(RoleNone) && n == strings.ToLower(name) {
+			return Role(i), nil
+		}
+	}
+	return RoleNone, fmt.Errorf("Unknown role %s", name)
+}
+
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Role) UnmarshalText(text []byte) (err error) {
+	*r, err = ParseRole(string(text))
+	return
+}
+
+const (
+	// DefaultUser and DefaultPassword are the credentials created when no
+	// user file exists yet.
+	DefaultUser     = "admin"
+	DefaultPassword = "admin"
+)
+
+var (
+	ErrNoDataFile         = errors.New("Password File Path Not defined.")
+	ErrInvalidCredentials = errors.New("Invalid Password")
+	ErrUnknownUser        = errors.New("Unknown user")
+	ErrUserExists         = errors.New("User already exists")
+	ErrLastAdmin          = errors.New("At least one enabled admin is required")
+	ErrForbidden          = errors.New("Permission denied")
+
+	ErrInvalidCurrentPassword = errors.New("Invalid Current Password")
+)
+
+type User struct {
+	Name     string `json:"name"`
+	Hash     string `json:"hash"`
+	Role     Role   `json:"role"`
+	Disabled bool   `json:"disabled"`
+	// MustChange is set for default and administrator assigned passwords,
+	// which have to be changed before the user can do anything else.
+	MustChange bool `json:"mustChange,omitempty"`
+}
+
+type userFile struct {
+	Users []*User `json:"users"`
+}
+
+// UserStore keeps the user accounts in the file at path. Older releases kept
+// a single bcrypt hash in that file, such a file is read as the admin user.
+type UserStore struct {
+	mutex   sync.Mutex
+	path    string
+	users   map[string]*User
+	policy  PasswordPolicy
+	hashing HashConfig
+}
+
+// Users is the store used by the server.
+var Users = NewUserStore(os.Getenv("DATA_FILE"))
+
+func NewUserStore(path string) *UserStore {
+	return &UserStore{
+		path:    path,
+		policy:  DefaultPasswordPolicy,
+		hashing: DefaultHashConfig,
+	}
+}
+
+// Configure sets the policy for new passwords and how they are hashed.
+func (s *UserStore) Configure(policy PasswordPolicy, hashing HashConfig) error {
+	if err := hashing.Validate(); err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.policy = policy
+	s.hashing = hashing
+	return nil
+}
+
+// load reads the user file the first time the store is used, creating the
+// default admin user if there is none.
+func (s *UserStore) load() error {
+	if s.users != nil {
+		return nil
+	}
+	if s.path == "" {
+		return ErrNoDataFile
+	}
+	users := make(map[string]*User)
+	saved, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		// first login, file not exists
+		hash, err := s.hashing.Hash(DefaultPassword)
+		if err != nil {
+			return err
+		}
+		users[DefaultUser] = &User{Name: DefaultUser, Hash: hash, Role: RoleAdmin, MustChange: true}
+		s.users = users
+		if err := s.save(); err != nil {
+			s.users = nil
+			return err
+		}
+		return nil
+	}
+	content := strings.TrimSpace(string(saved))
+	if strings.HasPrefix(content, "{") {
+		var file userFile
+		if err := json.Unmarshal(saved, &file); err != nil {
+			return fmt.Errorf("invalid user file (%s): %v", s.path, err)
+		}
+		for _, user := range file.Users {
+			users[user.Name] = user
+		}
+	} else {
+		// password hash written by an older release
+		users[DefaultUser] = &User{Name: DefaultUser, Hash: content, Role: RoleAdmin}
+	}
+	s.users = users
+	return nil
+}
+
+func (s *UserStore) save() error {
+	file := userFile{Users: make([]*User, 0, len(s.users))}
+	for _, user := range s.users {
+		file.Users = append(file.Users, user)
+	}
+	sort.Slice(file.Users, func(i, j int) bool {
+		return file.Users[i].Name < file.Users[j].Name
+	})
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// writeFileAtomic replaces the file at path with data, readable only by its
+// owner, so that a crash never leaves a partially written file behind.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err = tmp.Chmod(0600); err == nil {
+		if _, err = tmp.Write(data); err == nil {
+			err = tmp.Sync()
+		}
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Authenticate checks the password of an enabled user. Hashes made with
+// other settings than the configured ones are upgraded on the way. The
+// returned user has MustChange set if the password is still the default one.
+func (s *UserStore) Authenticate(name string, password string) (User, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.load(); err != nil {
+		return User{}, err
+	}
+	user, ok := s.users[name]
+	if !ok || user.Disabled {
+		return User{}, ErrInvalidCredentials
+	}
+	if !VerifyHash(user.Hash, password) {
+		return User{}, ErrInvalidCredentials
+	}
+	if s.hashing.NeedsRehash(user.Hash) {
+		if hash, err := s.hashing.Hash(password); err == nil {
+			old := user.Hash
+			user.Hash = hash
+			if s.save() != nil {
+				user.Hash = old
+			}
+		}
+	}
+	result := *user
+	if password == DefaultPassword {
+		result.MustChange = true
+	}
+	return result, nil
+}
+
+// Get returns the user called name.
+func (s *UserStore) Get(name string) (User, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.load() != nil {
+		return User{}, false
+	}
+	user, ok := s.users[name]
+	if !ok {
+		return User{}, false
+	}
+	return *user, true
+}
+
+// List returns all users ordered by name.
+func (s *UserStore) List() ([]User, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	result := make([]User, 0, len(s.users))
+	for _, user := range s.users {
+		result = append(result, *user)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// Create adds a new enabled user.
+func (s *UserStore) Create(name string, password string, role Role) error {
+	if name == "" {
+		return errors.New("User name is required")
+	}
+	if role == RoleNone {
+		return fmt.Errorf("Invalid role for user %s", name)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	if _, ok := s.users[name]; ok {
+		return ErrUserExists
+	}
+	if err := s.policy.Check(name, password); err != nil {
+		return err
+	}
+	hash, err := s.hashing.Hash(password)
+	if err != nil {
+		return err
+	}
+	s.users[name] = &User{Name: name, Hash: hash, Role: role, MustChange: true}
+	if err = s.save(); err != nil {
+		delete(s.users, name)
+	}
+	return err
+}
+
+// ChangePassword replaces the password of a user who knows the current one.
+func (s *UserStore) ChangePassword(name string, oldPassword string, newPassword string) error {
+	if _, err := s.Authenticate(name, oldPassword); err != nil {
+		if err == ErrInvalidCredentials {
+			return ErrInvalidCurrentPassword
+		}
+		return err
+	}
+	if newPassword == oldPassword || newPassword == DefaultPassword {
+		return errors.New("New password must differ from the current one")
+	}
+	return s.setPassword(name, newPassword, false)
+}
+
+// ResetPassword sets a new password for a user, which the user must change
+// at the next login.
+func (s *UserStore) ResetPassword(name string, password string) error {
+	return s.setPassword(name, password, true)
+}
+
+func (s *UserStore) setPassword(name string, password string, mustChange bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	user, ok := s.users[name]
+	if !ok {
+		return ErrUnknownUser
+	}
+	if err := s.policy.Check(name, password); err != nil {
+		return err
+	}
+	hash, err := s.hashing.Hash(password)
+	if err != nil {
+		return err
+	}
+	old := *user
+	user.Hash = hash
+	user.MustChange = mustChange
+	if err = s.save(); err != nil {
+		*user = old
+	}
+	return err
+}
+
+// SetDisabled enables or disables a user. The last enabled admin cannot be
+// disabled.
+func (s *UserStore) SetDisabled(name string, disabled bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	user, ok := s.users[name]
+	if !ok {
+		return ErrUnknownUser
+	}
+	if disabled && user.Role == RoleAdmin && !user.Disabled {
+		admins := 0
+		for _, u := range s.users {
+			if u.Role == RoleAdmin && !u.Disabled {
+				admins++
+			}
+		}
+		if admins == 1 {
+			return ErrLastAdmin
+		}
+	}
+	user.Disabled = disabled
+	return s.save()
+}
+
+// Authorize checks that the user called name is enabled and has at least
+// the given role.
+func (s *UserStore) Authorize(name string, role Role) error {
+	if role == RoleNone {
+		return nil
+	}
+	user, ok := s.Get(name)
+	if !ok || user.Disabled {
+		return ErrUnknownUser
+	}
+	if user.Role < role {
+		return ErrForbidden
+	}
+	return nil
+}
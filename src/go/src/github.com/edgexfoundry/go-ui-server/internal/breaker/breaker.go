@@ -0,0 +1,251 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package breaker stops calling services that keep failing. A breaker opens
+// after Failures failed calls in a row and fails calls fast while open.
+// After OpenTime it lets a single call through to probe the service, which
+// closes the breaker if it succeeds and opens it again if not. With a Prober
+// set, the breaker probes the service itself once OpenTime has passed, so
+// that it closes again without waiting for a call.
+package breaker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// State is the state of a breaker.
+type State string
+
+const (
+	// Closed lets all calls through
+	Closed State = "closed"
+	// Open fails all calls until it is time to probe the service
+	Open State = "open"
+	// HalfOpen lets a call through to probe the service and fails the others
+	HalfOpen State = "half-open"
+)
+
+// Config sets when breakers open and for how long.
+type Config struct {
+	Failures int
+	OpenTime time.Duration
+}
+
+var DefaultConfig = Config{
+	Failures: 5,
+	OpenTime: 30 * time.Second,
+}
+
+// withDefaults returns config with the default of each setting not set.
+func (config Config) withDefaults() Config {
+	if config.Failures <= 0 {
+		config.Failures = DefaultConfig.Failures
+	}
+	if config.OpenTime <= 0 {
+		config.OpenTime = DefaultConfig.OpenTime
+	}
+	return config
+}
+
+// OpenError is returned for calls failed fast by an open breaker.
+type OpenError struct {
+	Failures   int
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("not called after %d failures in a row, next attempt in %d seconds",
+		e.Failures, int(e.RetryAfter.Seconds()+0.5))
+}
+
+// Status is the state of the breaker of a service.
+type Status struct {
+	Name     string
+	State    State
+	Failures int
+	// LastError is the reason of the last failure
+	LastError   string
+	LastFailure time.Time
+	// Until is when the service is probed next, while the breaker is not
+	// closed
+	Until time.Time
+}
+
+// Prober checks whether the service name is available again.
+type Prober func(name string) error
+
+// Breaker tracks the failed calls to a service.
+type Breaker struct {
+	mutex  sync.Mutex
+	set    *Set
+	status Status
+	// timer runs the next probe while the breaker is open
+	timer *time.Timer
+}
+
+// Check returns an OpenError if the service must not be called now. Once the
+// open time has passed, a call is let through to probe the service, and
+// another one each time the open time passes again without an answer.
+func (b *Breaker) Check() error {
+	config := b.set.Config()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.status.State == Closed {
+		return nil
+	}
+	now := time.Now()
+	if now.Before(b.status.Until) {
+		return &OpenError{Failures: b.status.Failures, RetryAfter: b.status.Until.Sub(now)}
+	}
+	b.status.State = HalfOpen
+	b.status.Until = now.Add(config.OpenTime)
+	// the probing call may not tell how it went if it is cancelled
+	b.schedule(config.OpenTime)
+	return nil
+}
+
+// Succeeded closes the breaker.
+func (b *Breaker) Succeeded() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.status.State = Closed
+	b.status.Failures = 0
+	b.status.Until = time.Time{}
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+}
+
+// Failed counts a failed call for reason, opening the breaker after too many
+// of them or if the call probed the service.
+func (b *Breaker) Failed(reason string) {
+	config := b.set.Config()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := time.Now()
+	b.status.Failures++
+	b.status.LastError = reason
+	b.status.LastFailure = now
+	if b.status.State == HalfOpen || b.status.Failures >= config.Failures {
+		b.status.State = Open
+		b.status.Until = now.Add(config.OpenTime)
+		b.schedule(config.OpenTime)
+	}
+}
+
+// schedule probes the service after wait if the set has a prober. The caller
+// holds the mutex.
+func (b *Breaker) schedule(wait time.Duration) {
+	if b.set.Prober() == nil {
+		return
+	}
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.timer = time.AfterFunc(wait, b.probe)
+}
+
+// probe lets the prober of the set probe the service if the breaker is
+// still open and its open time has passed, and closes the breaker if the
+// service is available or opens it again if not.
+func (b *Breaker) probe() {
+	prober := b.set.Prober()
+	config := b.set.Config()
+	b.mutex.Lock()
+	b.timer = nil
+	if prober == nil || b.status.State == Closed {
+		b.mutex.Unlock()
+		return
+	}
+	now := time.Now()
+	if now.Before(b.status.Until) {
+		// a call is probing the service, or the breaker opened again
+		b.schedule(b.status.Until.Sub(now))
+		b.mutex.Unlock()
+		return
+	}
+	b.status.State = HalfOpen
+	b.status.Until = now.Add(config.OpenTime)
+	b.schedule(config.OpenTime)
+	name := b.status.Name
+	b.mutex.Unlock()
+
+	if err := prober(name); err != nil {
+		b.Failed(err.Error())
+	} else {
+		b.Succeeded()
+	}
+}
+
+// Status returns the state of the breaker.
+func (b *Breaker) Status() Status {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.status
+}
+
+// Set holds a breaker per service, all configured alike.
+type Set struct {
+	mutex    sync.Mutex
+	config   Config
+	prober   Prober
+	breakers map[string]*Breaker
+}
+
+func NewSet(config Config) *Set {
+	return &Set{
+		config:   config.withDefaults(),
+		breakers: make(map[string]*Breaker),
+	}
+}
+
+// Configure changes when the breakers open and for how long, keeping the
+// failures counted so far.
+func (s *Set) Configure(config Config) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.config = config.withDefaults()
+}
+
+// SetProber makes the breakers probe their services with prober once their
+// open time has passed, or stop probing them if it is nil.
+func (s *Set) SetProber(prober Prober) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.prober = prober
+}
+
+// Prober returns the prober of the breakers, nil if there is none.
+func (s *Set) Prober() Prober {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.prober
+}
+
+// Config returns the configuration of the breakers.
+func (s *Set) Config() Config {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.config
+}
+
+// Get returns the breaker of the service name.
+func (s *Set) Get(name string) *Breaker {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	b, ok := s.breakers[name]
+	if !ok {
+		b = &Breaker{set: s, status: Status{Name: name, State: Closed}}
+		s.breakers[name] = b
+	}
+	return b
+}
+
+// Reset closes the breaker of the service name, such as when it moved.
+func (s *Set) Reset(name string) {
+	s.Get(name).Succeeded()
+}
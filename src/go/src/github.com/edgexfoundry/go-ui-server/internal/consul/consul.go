@@ -0,0 +1,240 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config locates the Consul agent and names the service each client is
+// registered as.
+type Config struct {
+	// Enabled resolves the clients from Consul instead of [Clients]
+	Enabled bool
+	Host    string
+	Port    int
+	// Protocol is "http" or "https"
+	Protocol string
+	// Token is sent as ACL token if set
+	Token string
+	// WaitTime is how long, in seconds, a watch waits for a change before
+	// asking again, 60 by default
+	WaitTime int
+	// RetryInterval is how long, in seconds, to wait after Consul could not
+	// be reached, 5 by default
+	RetryInterval int
+	// Services maps the client names of [Clients] to Consul service names,
+	// those not listed keep their static settings
+	Services map[string]string
+}
+
+// DefaultServices are the names the EdgeX services register as.
+var DefaultServices = map[string]string{
+	"Data":          "edgex-core-data",
+	"Metadata":      "edgex-core-metadata",
+	"Command":       "edgex-core-command",
+	"Logging":       "edgex-support-logging",
+	"Notifications": "edgex-support-notifications",
+	"Scheduler":     "edgex-support-scheduler",
+	"Export":        "edgex-export-client",
+}
+
+// Instance is a healthy instance of a service.
+type Instance struct {
+	Node    string
+	ID      string
+	Address string
+	Port    int
+}
+
+func (i *Instance) Endpoint() string {
+	return net.JoinHostPort(i.Address, strconv.Itoa(i.Port))
+}
+
+// Handler is told the instance a service should be called at, nil if it
+// has none that is healthy or Consul cannot be reached.
+type Handler func(service string, instance *Instance, err error)
+
+// Client asks a Consul agent for the healthy instances of services.
+type Client struct {
+	config Config
+	base   string
+	http   *http.Client
+}
+
+type serviceEntry struct {
+	Node struct {
+		Node    string
+		Address string
+	}
+	Service struct {
+		ID      string
+		Service string
+		Address string
+		Port    int
+	}
+}
+
+func New(config Config) (*Client, error) {
+	if config.Host == "" {
+		config.Host = "localhost"
+	}
+	if config.Port == 0 {
+		config.Port = 8500
+	}
+	if config.Protocol == "" {
+		config.Protocol = "http"
+	}
+	config.Protocol = strings.ToLower(config.Protocol)
+	if config.Protocol != "http" && config.Protocol != "https" {
+		return nil, fmt.Errorf("invalid registry protocol %q", config.Protocol)
+	}
+	if config.WaitTime <= 0 {
+		config.WaitTime = 60
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = 5
+	}
+	if len(config.Services) == 0 {
+		config.Services = DefaultServices
+	}
+	return &Client{
+		config: config,
+		base:   config.Protocol + "://" + net.JoinHostPort(config.Host, strconv.Itoa(config.Port)),
+		// a blocking query may take up to WaitTime plus a sixteenth of it
+		http: &http.Client{Timeout: time.Duration(config.WaitTime)*time.Second*17/16 + 10*time.Second},
+	}, nil
+}
+
+func (c *Client) Config() Config {
+	return c.config
+}
+
+// Healthy returns the instances of the Consul service name passing their
+// health checks, ordered by node and id, and the index of the result. If
+// index is not 0 the call blocks until the result changes from the one at
+// index or the wait time is over.
+func (c *Client) Healthy(ctx context.Context, name string, index uint64) ([]Instance, uint64, error) {
+	query := url.Values{}
+	query.Set("passing", "true")
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", strconv.Itoa(c.config.WaitTime)+"s")
+	}
+	req, err := http.NewRequest(http.MethodGet, c.base+"/v1/health/service/"+url.PathEscape(name)+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if c.config.Token != "" {
+		req.Header.Set("X-Consul-Token", c.config.Token)
+	}
+	resp, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("consul returned %s for service %s", resp.Status, name)
+	}
+	var entries []serviceEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, 0, fmt.Errorf("invalid consul response for service %s: %v", name, err)
+	}
+	next, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	instances := make([]Instance, 0, len(entries))
+	for _, e := range entries {
+		address := e.Service.Address
+		if address == "" {
+			address = e.Node.Address
+		}
+		instances = append(instances, Instance{Node: e.Node.Node, ID: e.Service.ID, Address: address, Port: e.Service.Port})
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].Node != instances[j].Node {
+			return instances[i].Node < instances[j].Node
+		}
+		return instances[i].ID < instances[j].ID
+	})
+	return instances, next, nil
+}
+
+// Resolve returns the first healthy instance of each configured service,
+// by client name. Clients whose service cannot be resolved are missing.
+func (c *Client) Resolve(ctx context.Context) map[string]*Instance {
+	result := make(map[string]*Instance)
+	for client, name := range c.config.Services {
+		instances, _, err := c.Healthy(ctx, name, 0)
+		if err == nil && len(instances) > 0 {
+			result[client] = &instances[0]
+		}
+	}
+	return result
+}
+
+// Watch follows the healthy instances of each configured service until ctx
+// is done, calling handler with the client name whenever the instance to
+// use changes.
+func (c *Client) Watch(ctx context.Context, handler Handler) {
+	for client, name := range c.config.Services {
+		go c.watch(ctx, client, name, handler)
+	}
+}
+
+func (c *Client) watch(ctx context.Context, client string, name string, handler Handler) {
+	var index uint64
+	var current *Instance
+	first := true
+	retry := time.Duration(c.config.RetryInterval) * time.Second
+	for {
+		instances, next, err := c.Healthy(ctx, name, index)
+		if ctx.Err() != nil {
+			return
+		}
+		var instance *Instance
+		if err == nil && len(instances) > 0 {
+			instance = &instances[0]
+		}
+		if err == nil && len(instances) == 0 {
+			err = fmt.Errorf("no healthy instance of service %s", name)
+		}
+		if first || !sameInstance(instance, current) {
+			handler(client, instance, err)
+			current = instance
+			first = false
+		}
+		if next == 0 {
+			// Consul cannot be reached or did not return an index to block
+			// on, ask again later
+			index = 0
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retry):
+			}
+			continue
+		}
+		// the index may go backwards, for example after Consul restarted
+		if next < index {
+			next = 0
+		}
+		index = next
+	}
+}
+
+func sameInstance(a *Instance, b *Instance) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Address == b.Address && a.Port == b.Port
+}
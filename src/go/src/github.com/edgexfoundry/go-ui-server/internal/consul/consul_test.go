@@ -0,0 +1,213 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package consul
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-ui-server/internal/consul/consultest"
+)
+
+func entry(node string, id string, address string, port int) consultest.Entry {
+	return consultest.Entry{Node: node, NodeAddress: "192.0.2.1", ID: id, Address: address, Port: port}
+}
+
+func newAgent(t *testing.T, index uint64, entries ...consultest.Entry) *consultest.Agent {
+	return consultest.NewAgent(t, "edgex-core-data", index, entries...)
+}
+
+func newClient(t *testing.T, agent *consultest.Agent) *Client {
+	host, port := agent.HostPort()
+	c, err := New(Config{
+		Enabled:       true,
+		Host:          host,
+		Port:          port,
+		Token:         "acl-token",
+		WaitTime:      1,
+		RetryInterval: 1,
+		Services:      map[string]string{"Data": "edgex-core-data"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// change is a call of a watch handler.
+type change struct {
+	client   string
+	instance *Instance
+	err      error
+}
+
+// watch starts watching the services of c and returns the calls of its
+// handler.
+func watch(t *testing.T, c *Client) <-chan change {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	changes := make(chan change, 10)
+	c.Watch(ctx, func(client string, instance *Instance, err error) {
+		changes <- change{client, instance, err}
+	})
+	return changes
+}
+
+func next(t *testing.T, changes <-chan change) change {
+	select {
+	case c := <-changes:
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler not called")
+		return change{}
+	}
+}
+
+func noChange(t *testing.T, changes <-chan change, wait time.Duration) {
+	select {
+	case c := <-changes:
+		t.Fatalf("handler called with %+v", c)
+	case <-time.After(wait):
+	}
+}
+
+func TestHealthy(t *testing.T) {
+	agent := newAgent(t, 7,
+		entry("node-b", "data-1", "10.0.0.2", 48080),
+		entry("node-a", "data-2", "", 48081),
+		entry("node-a", "data-1", "10.0.0.1", 48080))
+	c := newClient(t, agent)
+
+	instances, index, err := c.Healthy(context.Background(), "edgex-core-data", 0)
+	if err != nil {
+		t.Fatalf("Healthy: %v", err)
+	}
+	if index != 7 {
+		t.Errorf("index %d, want 7", index)
+	}
+	want := []Instance{
+		{Node: "node-a", ID: "data-1", Address: "10.0.0.1", Port: 48080},
+		{Node: "node-a", ID: "data-2", Address: "192.0.2.1", Port: 48081},
+		{Node: "node-b", ID: "data-1", Address: "10.0.0.2", Port: 48080},
+	}
+	if len(instances) != len(want) {
+		t.Fatalf("instances %+v, want %+v", instances, want)
+	}
+	for i := range want {
+		if instances[i] != want[i] {
+			t.Errorf("instance %d is %+v, want %+v", i, instances[i], want[i])
+		}
+	}
+	query := agent.Queries()[0]
+	if query.Get("passing") != "true" || query.Get("index") != "" || query.Get("wait") != "" {
+		t.Errorf("query %v, want only passing instances without blocking", query)
+	}
+	if token := agent.Token(); token != "acl-token" {
+		t.Errorf("token %q, want acl-token", token)
+	}
+
+	agent.Set(8, http.StatusInternalServerError)
+	if _, _, err := c.Healthy(context.Background(), "edgex-core-data", 0); err == nil {
+		t.Error("Healthy succeeded on a failed Consul query")
+	}
+}
+
+func TestHealthyBlocks(t *testing.T) {
+	agent := newAgent(t, 5, entry("n", "data", "10.0.0.1", 48080))
+	c := newClient(t, agent)
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		agent.Set(6, http.StatusOK, entry("n", "data", "10.0.0.9", 48080))
+	}()
+	start := time.Now()
+	instances, index, err := c.Healthy(context.Background(), "edgex-core-data", 5)
+	if err != nil {
+		t.Fatalf("Healthy: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Healthy returned after %v without waiting for a change", elapsed)
+	}
+	if index != 6 || len(instances) != 1 || instances[0].Address != "10.0.0.9" {
+		t.Errorf("Healthy returned %+v at index %d, want 10.0.0.9 at 6", instances, index)
+	}
+	if query := agent.Queries()[0]; query.Get("index") != "5" || query.Get("wait") != "1s" {
+		t.Errorf("query %v, want index 5 and wait 1s", query)
+	}
+
+	// without a change the query returns once the wait time is over
+	start = time.Now()
+	if _, index, err = c.Healthy(context.Background(), "edgex-core-data", 6); err != nil || index != 6 {
+		t.Errorf("Healthy returned index %d, %v, want 6", index, err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Healthy returned after %v, before the wait time", elapsed)
+	}
+}
+
+func TestWatchSwitchesInstance(t *testing.T) {
+	agent := newAgent(t, 10, entry("n", "data-1", "10.0.0.1", 48080))
+	changes := watch(t, newClient(t, agent))
+
+	c := next(t, changes)
+	if c.client != "Data" || c.instance == nil || c.instance.Endpoint() != "10.0.0.1:48080" {
+		t.Fatalf("first instance %+v, want Data at 10.0.0.1:48080", c)
+	}
+
+	// another instance passing its checks does not move the client
+	agent.Set(11, http.StatusOK, entry("n", "data-1", "10.0.0.1", 48080), entry("o", "data-2", "10.0.0.2", 48080))
+	noChange(t, changes, 300*time.Millisecond)
+
+	agent.Set(12, http.StatusOK, entry("o", "data-2", "10.0.0.2", 48080))
+	if c = next(t, changes); c.instance == nil || c.instance.Endpoint() != "10.0.0.2:48080" {
+		t.Fatalf("instance %+v, want 10.0.0.2:48080", c.instance)
+	}
+
+	agent.Set(13, http.StatusOK)
+	if c = next(t, changes); c.instance != nil || c.err == nil {
+		t.Fatalf("no healthy instance reported as %+v", c)
+	}
+}
+
+func TestWatchIndexBackwards(t *testing.T) {
+	agent := newAgent(t, 10, entry("n", "data", "10.0.0.1", 48080))
+	changes := watch(t, newClient(t, agent))
+	next(t, changes)
+
+	// Consul restarted and counts from a lower index
+	agent.Set(3, http.StatusOK, entry("n", "data", "10.0.0.3", 48080))
+	if c := next(t, changes); c.instance == nil || c.instance.Endpoint() != "10.0.0.3:48080" {
+		t.Fatalf("instance %+v, want 10.0.0.3:48080", c.instance)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(agent.Indexes()) < 4 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	want := []string{"", "10", "", "3"}
+	got := agent.Indexes()
+	if len(got) < len(want) {
+		t.Fatalf("queries with index %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("queries with index %q, want %q", got[:len(want)], want)
+		}
+	}
+}
+
+func TestWatchUnreachable(t *testing.T) {
+	agent := newAgent(t, 1, entry("n", "data", "10.0.0.1", 48080))
+	c := newClient(t, agent)
+	agent.Close()
+
+	changes := watch(t, c)
+	if change := next(t, changes); change.instance != nil || change.err == nil {
+		t.Fatalf("unreachable Consul reported as %+v", change)
+	}
+	// asking again after RetryInterval, without telling the handler again
+	noChange(t, changes, 1500*time.Millisecond)
+}
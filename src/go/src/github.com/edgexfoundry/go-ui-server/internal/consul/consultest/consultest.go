@@ -0,0 +1,146 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package consultest provides a stand-in Consul agent for tests.
+package consultest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Entry is a passing instance of the service.
+type Entry struct {
+	Node        string
+	NodeAddress string
+	ID          string
+	// Address is the address of the service, the node's if empty
+	Address string
+	Port    int
+}
+
+// Agent serves /v1/health/service/ for one service like a Consul agent:
+// queries with the current index block until the instances change or the
+// wait time is over.
+type Agent struct {
+	*httptest.Server
+	service string
+
+	mutex   sync.Mutex
+	index   uint64
+	status  int
+	entries []Entry
+	// changed is closed and replaced on every change
+	changed chan struct{}
+	// queries are the query strings received, in order
+	queries []url.Values
+	token   string
+}
+
+// NewAgent starts an agent serving entries of service at index, closed
+// when the test ends.
+func NewAgent(t testing.TB, service string, index uint64, entries ...Entry) *Agent {
+	a := &Agent{service: service, index: index, status: http.StatusOK, entries: entries, changed: make(chan struct{})}
+	a.Server = httptest.NewServer(http.HandlerFunc(a.serve))
+	t.Cleanup(a.Close)
+	return a
+}
+
+// HostPort returns the host and port the agent listens on.
+func (a *Agent) HostPort() (string, int) {
+	u, _ := url.Parse(a.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return u.Hostname(), port
+}
+
+func (a *Agent) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/health/service/"+a.service {
+		http.NotFound(w, r)
+		return
+	}
+	query := r.URL.Query()
+	a.mutex.Lock()
+	a.queries = append(a.queries, query)
+	a.token = r.Header.Get("X-Consul-Token")
+	index, changed := a.index, a.changed
+	a.mutex.Unlock()
+	if query.Get("index") == strconv.FormatUint(index, 10) {
+		wait, _ := time.ParseDuration(query.Get("wait"))
+		select {
+		case <-changed:
+		case <-time.After(wait):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.status != http.StatusOK {
+		w.WriteHeader(a.status)
+		return
+	}
+	type node struct {
+		Node    string
+		Address string
+	}
+	type service struct {
+		ID      string
+		Service string
+		Address string
+		Port    int
+	}
+	reply := make([]struct {
+		Node    node
+		Service service
+	}, len(a.entries))
+	for i, e := range a.entries {
+		reply[i].Node = node{e.Node, e.NodeAddress}
+		reply[i].Service = service{e.ID, a.service, e.Address, e.Port}
+	}
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(a.index, 10))
+	json.NewEncoder(w).Encode(reply)
+}
+
+// Set changes the instances, or makes the agent fail with status if it is
+// not 200, at index and wakes up the blocked queries.
+func (a *Agent) Set(index uint64, status int, entries ...Entry) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.index = index
+	a.status = status
+	a.entries = entries
+	close(a.changed)
+	a.changed = make(chan struct{})
+}
+
+// Queries returns the query strings received so far, in order.
+func (a *Agent) Queries() []url.Values {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return append([]url.Values(nil), a.queries...)
+}
+
+// Indexes returns the index of each query received so far, "" for those
+// without one.
+func (a *Agent) Indexes() []string {
+	queries := a.Queries()
+	result := make([]string, len(queries))
+	for i, q := range queries {
+		result[i] = q.Get("index")
+	}
+	return result
+}
+
+// Token returns the ACL token of the last query.
+func (a *Agent) Token() string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.token
+}
@@ -0,0 +1,80 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package edgex
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/edgexfoundry/go-ui-server/internal/audit"
+	"github.com/edgexfoundry/go-ui-server/internal/fulcro"
+	"github.com/russolsen/transit"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+	// maxAuditOffset bounds the matches held in memory to reach a page,
+	// older entries are found by narrowing the time range
+	maxAuditOffset = 100000
+)
+
+func fromMillis(ms int64) time.Time {
+	if ms <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+// ShowAudit returns a page of the audit log, newest first, optionally
+// filtered by user, mutation and a time range in milliseconds.
+func ShowAudit(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+	filter := audit.Filter{
+		User:     fulcro.GetString(args, "user"),
+		Mutation: fulcro.GetString(args, "mutation"),
+		From:     fromMillis(fulcro.GetIntOr(args, "start", 0)),
+		To:       fromMillis(fulcro.GetIntOr(args, "end", 0)),
+	}
+	offset := fulcro.GetIntOr(args, "offset", 0)
+	limit := fulcro.GetIntOr(args, "limit", defaultAuditPageSize)
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > maxAuditOffset {
+		offset = maxAuditOffset
+	}
+	if limit <= 0 {
+		limit = defaultAuditPageSize
+	}
+	if limit > maxAuditPageSize {
+		limit = maxAuditPageSize
+	}
+	entries, total, err := audit.Trail.Query(filter, int(offset), int(limit))
+	if err != nil {
+		return nil, err
+	}
+	content := make([]map[string]interface{}, len(entries))
+	for i, entry := range entries {
+		content[i] = map[string]interface{}{
+			"type":     transit.Keyword("audit-entry"),
+			"id":       transit.Keyword(strconv.Itoa(entry.Id)),
+			"time":     millis(entry.Time),
+			"user":     entry.User,
+			"session":  entry.Session,
+			"client":   entry.Client,
+			"mutation": entry.Mutation,
+			"args":     entry.Args,
+			"status":   entry.Status,
+			"error":    entry.Error,
+		}
+	}
+	result := map[string]interface{}{
+		"content": content,
+		"total":   total,
+		"offset":  offset,
+		"limit":   limit,
+	}
+	return fulcro.Keywordize(result, nil)
+}
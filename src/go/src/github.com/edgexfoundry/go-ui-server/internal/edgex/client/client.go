@@ -0,0 +1,151 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package client calls the REST APIs of the EdgeX services and decodes their
+// replies into typed models, with one method per operation the UI uses.
+package client
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/edgexfoundry/go-ui-server/internal/breaker"
+	"gopkg.in/resty.v1"
+)
+
+// The names of the EdgeX services given to Services
+const (
+	ServiceData          = "data"
+	ServiceMetadata      = "metadata"
+	ServiceLogging       = "logging"
+	ServiceCommand       = "command"
+	ServiceExport        = "export"
+	ServiceNotifications = "notifications"
+	ServiceScheduler     = "scheduler"
+)
+
+// The versions of the EdgeX REST APIs
+const (
+	APIv1 = "v1"
+	APIv2 = "v2"
+)
+
+// Services gives the REST client, the version and the base URL of the API,
+// ending with "/", how reads are retried and the circuit breaker of each
+// EdgeX service.
+type Services interface {
+	Client(service string) *resty.Client
+	APIVersion(service string) string
+	URL(service string) string
+	Retry(service string) Retry
+	Breaker(service string) *breaker.Breaker
+}
+
+// Client calls the EdgeX services. The requests it makes are bound to a
+// context, such as that of the UI request they are made for, and are
+// cancelled with it.
+type Client struct {
+	ctx      context.Context
+	services Services
+}
+
+// New returns a client calling services with the context ctx.
+func New(ctx context.Context, services Services) *Client {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &Client{ctx: ctx, services: services}
+}
+
+// request starts a request to service.
+func (c *Client) request(service string) *resty.Request {
+	client := c.services.Client(service)
+	if client == nil {
+		client = resty.DefaultClient
+	}
+	return client.R().SetContext(c.ctx)
+}
+
+// send makes a request to service with do, unless the breaker of service is
+// open, and tells the breaker whether the service was available. Requests
+// cancelled by the context tell nothing about the service.
+func (c *Client) send(service string, do func(r *resty.Request) (*resty.Response, error)) (*resty.Response, error) {
+	b := c.services.Breaker(service)
+	if err := b.Check(); err != nil {
+		return nil, err
+	}
+	resp, err := do(c.request(service))
+	switch {
+	case c.ctx.Err() != nil:
+	case err != nil:
+		b.Failed(err.Error())
+	case unavailable(resp):
+		b.Failed(resp.Status())
+	default:
+		b.Succeeded()
+	}
+	return resp, err
+}
+
+// url returns the URL of path in the API of service.
+func (c *Client) url(service string, path string) string {
+	return c.services.URL(service) + path
+}
+
+// get reads path of service into result for operation.
+func (c *Client) get(service string, operation string, path string, result interface{}) error {
+	resp, err := c.read(service, path)
+	if err = check(service, operation, resp, err); err != nil {
+		return err
+	}
+	return json.Unmarshal(resp.Body(), result)
+}
+
+// getText returns the body of path of service for operation.
+func (c *Client) getText(service string, operation string, path string) (string, error) {
+	resp, err := c.read(service, path)
+	if err = check(service, operation, resp, err); err != nil {
+		return "", err
+	}
+	return resp.String(), nil
+}
+
+// post sends body to path of service for operation and returns the reply,
+// the id of the object added for most operations.
+func (c *Client) post(service string, operation string, path string, body interface{}) (string, error) {
+	resp, err := c.send(service, func(r *resty.Request) (*resty.Response, error) {
+		return r.SetBody(body).Post(c.url(service, path))
+	})
+	if err = check(service, operation, resp, err); err != nil {
+		return "", err
+	}
+	return resp.String(), nil
+}
+
+// patch sends body to path of service for operation and returns the reply.
+func (c *Client) patch(service string, operation string, path string, body interface{}) (string, error) {
+	resp, err := c.send(service, func(r *resty.Request) (*resty.Response, error) {
+		return r.SetBody(body).Patch(c.url(service, path))
+	})
+	if err = check(service, operation, resp, err); err != nil {
+		return "", err
+	}
+	return resp.String(), nil
+}
+
+// put sends body to path of service for operation.
+func (c *Client) put(service string, operation string, path string, body interface{}) error {
+	resp, err := c.send(service, func(r *resty.Request) (*resty.Response, error) {
+		return r.SetBody(body).Put(c.url(service, path))
+	})
+	return check(service, operation, resp, err)
+}
+
+// delete deletes path of service for operation.
+func (c *Client) delete(service string, operation string, path string) error {
+	resp, err := c.send(service, func(r *resty.Request) (*resty.Response, error) {
+		return r.Delete(c.url(service, path))
+	})
+	return check(service, operation, resp, err)
+}
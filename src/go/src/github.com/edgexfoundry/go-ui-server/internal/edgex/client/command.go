@@ -0,0 +1,59 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import "net/url"
+
+// DeviceCommands returns the commands of the device id.
+func (c *Client) DeviceCommands(id string) (DeviceCommands, error) {
+	if c.v2(ServiceCommand) {
+		return c.deviceCommandsV2(id)
+	}
+	var device DeviceCommands
+	err := c.get(ServiceCommand, "DeviceCommands", "device/"+url.PathEscape(id), &device)
+	return device, err
+}
+
+// IssueGetCommand reads the values of the command commandId of the device
+// deviceId.
+func (c *Client) IssueGetCommand(deviceId string, commandId string) (Event, error) {
+	if c.v2(ServiceCommand) {
+		return c.issueGetCommandV2(deviceId, commandId)
+	}
+	var event Event
+	err := c.get(ServiceCommand, "IssueGetCommand", "device/"+url.PathEscape(deviceId)+"/command/"+url.PathEscape(commandId), &event)
+	return event, err
+}
+
+// IssueSetCommand sets the parameters of the command commandId of the device
+// deviceId to values.
+func (c *Client) IssueSetCommand(deviceId string, commandId string, values map[string]interface{}) error {
+	if c.v2(ServiceCommand) {
+		return c.put(ServiceCommand, "IssueSetCommand", commandPathV2(deviceId, commandId), values)
+	}
+	return c.put(ServiceCommand, "IssueSetCommand", "device/"+url.PathEscape(deviceId)+"/command/"+url.PathEscape(commandId), values)
+}
+
+func (c *Client) deviceCommandsV2(name string) (DeviceCommands, error) {
+	var reply struct {
+		DeviceCoreCommand deviceCoreCommandV2 `json:"deviceCoreCommand"`
+	}
+	err := c.get(ServiceCommand, "DeviceCommands", "device/name/"+url.PathEscape(name), &reply)
+	return reply.DeviceCoreCommand.model(), err
+}
+
+func (c *Client) issueGetCommandV2(deviceName string, commandName string) (Event, error) {
+	var reply struct {
+		Event eventV2 `json:"event"`
+	}
+	err := c.get(ServiceCommand, "IssueGetCommand", commandPathV2(deviceName, commandName), &reply)
+	return reply.Event.model(), err
+}
+
+// commandPathV2 returns the path of the command commandName of the device
+// deviceName.
+func commandPathV2(deviceName string, commandName string) string {
+	return "device/name/" + url.PathEscape(deviceName) + "/" + url.PathEscape(commandName)
+}
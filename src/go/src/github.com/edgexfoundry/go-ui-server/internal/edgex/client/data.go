@@ -0,0 +1,98 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"sort"
+	"time"
+)
+
+// DeviceReadings returns the readings of device created from from to to, in
+// milliseconds, oldest first.
+func (c *Client) DeviceReadings(device string, from int64, to int64) ([]Reading, error) {
+	if c.v2(ServiceData) {
+		return c.deviceReadingsV2(device, from, to)
+	}
+	var readings []Reading
+	ids := make(map[string]bool)
+	err := byTime(from, func(from int64) (int, int64, error) {
+		var batch []Reading
+		err := c.get(ServiceData, "DeviceReadings", "reading/"+timeRange(from, to, batchSize), &batch)
+		var last int64
+		for _, reading := range batch {
+			last = reading.Created
+			if reading.Device == device && !ids[reading.Id] {
+				ids[reading.Id] = true
+				readings = append(readings, reading)
+			}
+		}
+		return len(batch), last, err
+	})
+	return readings, err
+}
+
+// ValueDescriptors returns all value descriptors.
+func (c *Client) ValueDescriptors() ([]ValueDescriptor, error) {
+	if c.v2(ServiceData) {
+		return c.valueDescriptorsV2()
+	}
+	var descriptors []ValueDescriptor
+	err := c.get(ServiceData, "ValueDescriptors", "valuedescriptor", &descriptors)
+	return descriptors, err
+}
+
+// deviceReadingsV2 reads the readings by the time they were taken, in
+// nanoseconds.
+func (c *Client) deviceReadingsV2(device string, from int64, to int64) ([]Reading, error) {
+	var readings []Reading
+	ms := int64(time.Millisecond)
+	path := "reading/" + startEndV2(from*ms, to*ms)
+	err := byOffset(func(offset int) (int, int, error) {
+		var reply struct {
+			TotalCount int         `json:"totalCount"`
+			Readings   []readingV2 `json:"readings"`
+		}
+		err := c.get(ServiceData, "DeviceReadings", page(path, offset), &reply)
+		for _, reading := range reply.Readings {
+			if reading.DeviceName == device {
+				readings = append(readings, reading.model())
+			}
+		}
+		return len(reply.Readings), reply.TotalCount, err
+	})
+	sort.SliceStable(readings, func(i, j int) bool { return readings[i].Origin < readings[j].Origin })
+	return readings, err
+}
+
+// valueDescriptorsV2 returns a value descriptor for each name of the device
+// resources of all device profiles, as EdgeX 2.x has no value descriptors.
+func (c *Client) valueDescriptorsV2() ([]ValueDescriptor, error) {
+	profiles, err := c.DeviceProfiles()
+	if err != nil {
+		return nil, err
+	}
+	var descriptors []ValueDescriptor
+	names := make(map[string]bool)
+	for _, profile := range profiles {
+		for _, resource := range profile.DeviceResources {
+			if names[resource.Name] {
+				continue
+			}
+			names[resource.Name] = true
+			value := resource.Properties.Value
+			descriptors = append(descriptors, ValueDescriptor{
+				Id:           resource.Name,
+				Name:         resource.Name,
+				Description:  resource.Description,
+				Min:          value.Minimum,
+				Max:          value.Maximum,
+				DefaultValue: value.DefaultValue,
+				Type:         value.Type,
+				UomLabel:     resource.Properties.Units.DefaultValue,
+			})
+		}
+	}
+	return descriptors, nil
+}
@@ -0,0 +1,102 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/edgexfoundry/go-ui-server/internal/breaker"
+	"gopkg.in/resty.v1"
+)
+
+// maxMessage is the length to which the reply of a failed call is cut in
+// the message of its error.
+const maxMessage = 512
+
+// Error is a failed call to an EdgeX service: either the service could not
+// be reached, in which case Status is 0 and Err holds the cause, or it
+// answered with a status other than 2xx.
+type Error struct {
+	Service   string
+	Operation string
+	Status    int
+	Message   string
+	Err       error
+}
+
+func (e *Error) Error() string {
+	if e.Status == 0 {
+		return fmt.Sprintf("%s %s: %s", e.Service, e.Operation, e.Message)
+	}
+	return fmt.Sprintf("%s %s: %d %s: %s", e.Service, e.Operation, e.Status, http.StatusText(e.Status), e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Details returns the service, operation and status of the call to be
+// reported to the web client along with the message.
+func (e *Error) Details() map[string]interface{} {
+	return map[string]interface{}{
+		"service":   e.Service,
+		"operation": e.Operation,
+		"status":    e.Status,
+	}
+}
+
+// NotSent tells whether err is a call that never reached the service,
+// because its breaker is open or no connection could be made. Calls failing
+// otherwise may have been carried out by the service.
+func NotSent(err error) bool {
+	var open *breaker.OpenError
+	if errors.As(err, &open) {
+		return true
+	}
+	var op *net.OpError
+	return errors.As(err, &op) && (op.Op == "dial" || op.Op == "proxyconnect")
+}
+
+// check returns the error of the call operation to service that got resp
+// and err, nil if it succeeded.
+func check(service string, operation string, resp *resty.Response, err error) error {
+	if err != nil {
+		return &Error{Service: service, Operation: operation, Message: err.Error(), Err: err}
+	}
+	if resp.IsSuccess() {
+		return nil
+	}
+	return &Error{
+		Service:   service,
+		Operation: operation,
+		Status:    resp.StatusCode(),
+		Message:   replyMessage(resp),
+	}
+}
+
+// replyMessage returns the message of an error reply: the message field of
+// a JSON reply, the text of others or the status text if the body is empty.
+func replyMessage(resp *resty.Response) string {
+	body := resp.Body()
+	var reply struct {
+		Message string `json:"message"`
+	}
+	message := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &reply) == nil && reply.Message != "" {
+		message = reply.Message
+	}
+	if message == "" {
+		return http.StatusText(resp.StatusCode())
+	}
+	if len(message) > maxMessage {
+		message = message[:maxMessage] + "..."
+	}
+	return message
+}
@@ -0,0 +1,52 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import "net/url"
+
+// Registrations returns all export registrations. The export service is gone
+// from EdgeX 2.x, where there are none.
+func (c *Client) Registrations() ([]Registration, error) {
+	if c.v2(ServiceExport) {
+		return nil, nil
+	}
+	var registrations []Registration
+	err := c.get(ServiceExport, "Registrations", "registration", &registrations)
+	return registrations, err
+}
+
+// Registration returns the export registration id.
+func (c *Client) Registration(id string) (Registration, error) {
+	if c.v2(ServiceExport) {
+		return Registration{}, unsupported(ServiceExport, "Registration")
+	}
+	var registration Registration
+	err := c.get(ServiceExport, "Registration", "registration/"+url.PathEscape(id), &registration)
+	return registration, err
+}
+
+// AddRegistration adds registration and returns its id.
+func (c *Client) AddRegistration(registration Registration) (string, error) {
+	if c.v2(ServiceExport) {
+		return "", unsupported(ServiceExport, "AddRegistration")
+	}
+	return c.post(ServiceExport, "AddRegistration", "registration", registration)
+}
+
+// UpdateRegistration changes the registration with the id of registration.
+func (c *Client) UpdateRegistration(registration Registration) error {
+	if c.v2(ServiceExport) {
+		return unsupported(ServiceExport, "UpdateRegistration")
+	}
+	return c.put(ServiceExport, "UpdateRegistration", "registration", registration)
+}
+
+// DeleteRegistration deletes the export registration id.
+func (c *Client) DeleteRegistration(id string) error {
+	if c.v2(ServiceExport) {
+		return unsupported(ServiceExport, "DeleteRegistration")
+	}
+	return c.delete(ServiceExport, "DeleteRegistration", "registration/id/"+url.PathEscape(id))
+}
@@ -0,0 +1,38 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+// Logs returns the log entries created from from to to, in milliseconds,
+// oldest first. The logging service is gone from EdgeX 2.x, where there are
+// none.
+func (c *Client) Logs(from int64, to int64) ([]LogEntry, error) {
+	if c.v2(ServiceLogging) {
+		return nil, nil
+	}
+	var logs []LogEntry
+	// The entries have no id. Those created at the time the last batch ended
+	// are read again and skipped by counting those seen already.
+	var last int64
+	seen := 0
+	err := byTime(from, func(from int64) (int, int64, error) {
+		var batch []LogEntry
+		err := c.get(ServiceLogging, "Logs", "logs/"+timeRange(from, to, batchSize), &batch)
+		again, skip := last, seen
+		for _, entry := range batch {
+			if entry.Created == again && skip > 0 {
+				skip--
+				continue
+			}
+			if entry.Created != last {
+				last = entry.Created
+				seen = 0
+			}
+			seen++
+			logs = append(logs, entry)
+		}
+		return len(batch), last, err
+	})
+	return logs, err
+}
@@ -0,0 +1,248 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"net/url"
+
+	"gopkg.in/resty.v1"
+	"gopkg.in/yaml.v2"
+)
+
+// Devices returns all devices.
+func (c *Client) Devices() ([]Device, error) {
+	if c.v2(ServiceMetadata) {
+		return c.devicesV2()
+	}
+	var devices []Device
+	err := c.get(ServiceMetadata, "Devices", "device", &devices)
+	return devices, err
+}
+
+// Device returns the device id.
+func (c *Client) Device(id string) (Device, error) {
+	if c.v2(ServiceMetadata) {
+		return c.deviceV2(id)
+	}
+	var device Device
+	err := c.get(ServiceMetadata, "Device", "device/"+url.PathEscape(id), &device)
+	return device, err
+}
+
+// AddDevice adds device and returns its id.
+func (c *Client) AddDevice(device Device) (string, error) {
+	if c.v2(ServiceMetadata) {
+		return c.addDeviceV2(device)
+	}
+	return c.post(ServiceMetadata, "AddDevice", "device", device)
+}
+
+// UpdateDeviceAdminState locks or unlocks the device id.
+func (c *Client) UpdateDeviceAdminState(id string, state string) error {
+	if c.v2(ServiceMetadata) {
+		return c.patchV2(ServiceMetadata, "UpdateDeviceAdminState", "device", "device",
+			map[string]string{"name": id, "adminState": state})
+	}
+	return c.put(ServiceMetadata, "UpdateDeviceAdminState", "device/"+url.PathEscape(id), map[string]string{"adminState": state})
+}
+
+// DeleteDevice deletes the device id.
+func (c *Client) DeleteDevice(id string) error {
+	if c.v2(ServiceMetadata) {
+		return c.delete(ServiceMetadata, "DeleteDevice", "device/name/"+url.PathEscape(id))
+	}
+	return c.delete(ServiceMetadata, "DeleteDevice", "device/id/"+url.PathEscape(id))
+}
+
+// DeviceServices returns all device services.
+func (c *Client) DeviceServices() ([]DeviceService, error) {
+	if c.v2(ServiceMetadata) {
+		return c.deviceServicesV2()
+	}
+	var services []DeviceService
+	err := c.get(ServiceMetadata, "DeviceServices", "deviceservice", &services)
+	return services, err
+}
+
+// ScheduleEvents returns all schedule events of the metadata service, none
+// with the v2 API.
+func (c *Client) ScheduleEvents() ([]ScheduleEvent, error) {
+	if c.v2(ServiceMetadata) {
+		return nil, nil
+	}
+	var events []ScheduleEvent
+	err := c.get(ServiceMetadata, "ScheduleEvents", "scheduleevent", &events)
+	return events, err
+}
+
+// Addressables returns all addressables, none with the v2 API.
+func (c *Client) Addressables() ([]Addressable, error) {
+	if c.v2(ServiceMetadata) {
+		return nil, nil
+	}
+	var addressables []Addressable
+	err := c.get(ServiceMetadata, "Addressables", "addressable", &addressables)
+	return addressables, err
+}
+
+// Addressable returns the addressable id.
+func (c *Client) Addressable(id string) (Addressable, error) {
+	if c.v2(ServiceMetadata) {
+		return Addressable{}, unsupported(ServiceMetadata, "Addressable")
+	}
+	var addressable Addressable
+	err := c.get(ServiceMetadata, "Addressable", "addressable/"+url.PathEscape(id), &addressable)
+	return addressable, err
+}
+
+// AddAddressable adds addressable and returns its id.
+func (c *Client) AddAddressable(addressable Addressable) (string, error) {
+	if c.v2(ServiceMetadata) {
+		return "", unsupported(ServiceMetadata, "AddAddressable")
+	}
+	return c.post(ServiceMetadata, "AddAddressable", "addressable", addressable)
+}
+
+// UpdateAddressable changes the addressable with the id of addressable.
+func (c *Client) UpdateAddressable(addressable Addressable) error {
+	if c.v2(ServiceMetadata) {
+		return unsupported(ServiceMetadata, "UpdateAddressable")
+	}
+	return c.put(ServiceMetadata, "UpdateAddressable", "addressable", addressable)
+}
+
+// DeleteAddressable deletes the addressable id.
+func (c *Client) DeleteAddressable(id string) error {
+	if c.v2(ServiceMetadata) {
+		return unsupported(ServiceMetadata, "DeleteAddressable")
+	}
+	return c.delete(ServiceMetadata, "DeleteAddressable", "addressable/id/"+url.PathEscape(id))
+}
+
+// DeviceProfiles returns all device profiles.
+func (c *Client) DeviceProfiles() ([]DeviceProfile, error) {
+	if c.v2(ServiceMetadata) {
+		return c.deviceProfilesV2()
+	}
+	var profiles []DeviceProfile
+	err := c.get(ServiceMetadata, "DeviceProfiles", "deviceprofile", &profiles)
+	return profiles, err
+}
+
+// DeviceProfileYaml returns the device profile id as YAML.
+func (c *Client) DeviceProfileYaml(id string) (string, error) {
+	if c.v2(ServiceMetadata) {
+		return c.deviceProfileYamlV2(id)
+	}
+	return c.getText(ServiceMetadata, "DeviceProfileYaml", "deviceprofile/yaml/"+url.PathEscape(id))
+}
+
+// UploadDeviceProfile adds the device profile in the YAML file fileName.
+func (c *Client) UploadDeviceProfile(fileName string) error {
+	if c.v2(ServiceMetadata) {
+		return c.uploadDeviceProfileV2(fileName)
+	}
+	resp, err := c.send(ServiceMetadata, func(r *resty.Request) (*resty.Response, error) {
+		return r.SetHeader("Content-Type", "application/x-yaml").
+			SetFile("file", fileName).
+			Post(c.url(ServiceMetadata, "deviceprofile/uploadfile"))
+	})
+	return check(ServiceMetadata, "UploadDeviceProfile", resp, err)
+}
+
+// DeleteDeviceProfile deletes the device profile id.
+func (c *Client) DeleteDeviceProfile(id string) error {
+	if c.v2(ServiceMetadata) {
+		return c.delete(ServiceMetadata, "DeleteDeviceProfile", "deviceprofile/name/"+url.PathEscape(id))
+	}
+	return c.delete(ServiceMetadata, "DeleteDeviceProfile", "deviceprofile/id/"+url.PathEscape(id))
+}
+
+func (c *Client) devicesV2() ([]Device, error) {
+	var devices []Device
+	err := byOffset(func(offset int) (int, int, error) {
+		var reply struct {
+			TotalCount int        `json:"totalCount"`
+			Devices    []deviceV2 `json:"devices"`
+		}
+		err := c.get(ServiceMetadata, "Devices", page("device/all", offset), &reply)
+		for _, device := range reply.Devices {
+			devices = append(devices, device.model())
+		}
+		return len(reply.Devices), reply.TotalCount, err
+	})
+	return devices, err
+}
+
+func (c *Client) deviceV2(name string) (Device, error) {
+	var reply struct {
+		Device deviceV2 `json:"device"`
+	}
+	err := c.get(ServiceMetadata, "Device", "device/name/"+url.PathEscape(name), &reply)
+	return reply.Device.model(), err
+}
+
+// addDeviceV2 returns the name of the device added, its id in the models.
+func (c *Client) addDeviceV2(device Device) (string, error) {
+	if _, err := c.postV2(ServiceMetadata, "AddDevice", "device", "device", newDeviceV2(device)); err != nil {
+		return "", err
+	}
+	return device.Name, nil
+}
+
+func (c *Client) deviceServicesV2() ([]DeviceService, error) {
+	var services []DeviceService
+	err := byOffset(func(offset int) (int, int, error) {
+		var reply struct {
+			TotalCount int               `json:"totalCount"`
+			Services   []deviceServiceV2 `json:"services"`
+		}
+		err := c.get(ServiceMetadata, "DeviceServices", page("deviceservice/all", offset), &reply)
+		for _, service := range reply.Services {
+			services = append(services, service.model())
+		}
+		return len(reply.Services), reply.TotalCount, err
+	})
+	return services, err
+}
+
+func (c *Client) deviceProfilesV2() ([]DeviceProfile, error) {
+	var profiles []DeviceProfile
+	err := byOffset(func(offset int) (int, int, error) {
+		var reply struct {
+			TotalCount int               `json:"totalCount"`
+			Profiles   []deviceProfileV2 `json:"profiles"`
+		}
+		err := c.get(ServiceMetadata, "DeviceProfiles", page("deviceprofile/all", offset), &reply)
+		for _, profile := range reply.Profiles {
+			profiles = append(profiles, profile.model())
+		}
+		return len(reply.Profiles), reply.TotalCount, err
+	})
+	return profiles, err
+}
+
+// deviceProfileYamlV2 writes the device profile name as YAML, which the v2
+// API only reads.
+func (c *Client) deviceProfileYamlV2(name string) (string, error) {
+	var reply struct {
+		Profile deviceProfileV2 `json:"profile"`
+	}
+	if err := c.get(ServiceMetadata, "DeviceProfileYaml", "deviceprofile/name/"+url.PathEscape(name), &reply); err != nil {
+		return "", err
+	}
+	out, err := yaml.Marshal(reply.Profile)
+	if err != nil {
+		return "", &Error{Service: ServiceMetadata, Operation: "DeviceProfileYaml", Message: err.Error(), Err: err}
+	}
+	return string(out), nil
+}
+
+func (c *Client) uploadDeviceProfileV2(fileName string) error {
+	resp, err := c.send(ServiceMetadata, func(r *resty.Request) (*resty.Response, error) {
+		return r.SetFile("file", fileName).Post(c.url(ServiceMetadata, "deviceprofile/uploadfile"))
+	})
+	return check(ServiceMetadata, "UploadDeviceProfile", resp, err)
+}
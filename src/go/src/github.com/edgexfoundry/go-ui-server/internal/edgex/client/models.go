@@ -0,0 +1,344 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+// Timestamps are the times, in milliseconds, common to the EdgeX objects.
+type Timestamps struct {
+	Created  int64 `json:"created,omitempty"`
+	Modified int64 `json:"modified,omitempty"`
+	Origin   int64 `json:"origin,omitempty"`
+}
+
+// Addressable is an address of a device, service or export, with the
+// credentials to use it.
+type Addressable struct {
+	Timestamps
+	Id        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Protocol  string `json:"protocol,omitempty"`
+	Method    string `json:"method,omitempty"`
+	Address   string `json:"address"`
+	Port      int    `json:"port,omitempty"`
+	Path      string `json:"path"`
+	Publisher string `json:"publisher,omitempty"`
+	Topic     string `json:"topic,omitempty"`
+	User      string `json:"user,omitempty"`
+	Password  string `json:"password,omitempty"`
+	Cert      string `json:"cert,omitempty"`
+	Key       string `json:"key,omitempty"`
+	BaseURL   string `json:"baseURL,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// DeviceService is a device service of the metadata service.
+type DeviceService struct {
+	Timestamps
+	Id             string       `json:"id,omitempty"`
+	Name           string       `json:"name,omitempty"`
+	Description    string       `json:"description,omitempty"`
+	Labels         []string     `json:"labels,omitempty"`
+	AdminState     string       `json:"adminState,omitempty"`
+	OperatingState string       `json:"operatingState,omitempty"`
+	LastConnected  int64        `json:"lastConnected,omitempty"`
+	LastReported   int64        `json:"lastReported,omitempty"`
+	Addressable    *Addressable `json:"addressable,omitempty"`
+}
+
+// Units are the units of the value of a device resource.
+type Units struct {
+	Type         string `json:"type,omitempty"`
+	ReadWrite    string `json:"readWrite,omitempty"`
+	DefaultValue string `json:"defaultValue,omitempty"`
+}
+
+// PropertyValue describes the value of a device resource.
+type PropertyValue struct {
+	Type          string `json:"type,omitempty"`
+	ReadWrite     string `json:"readWrite,omitempty"`
+	Minimum       string `json:"minimum,omitempty"`
+	Maximum       string `json:"maximum,omitempty"`
+	DefaultValue  string `json:"defaultValue,omitempty"`
+	Size          string `json:"size,omitempty"`
+	Mask          string `json:"mask,omitempty"`
+	Shift         string `json:"shift,omitempty"`
+	Scale         string `json:"scale,omitempty"`
+	Offset        string `json:"offset,omitempty"`
+	Base          string `json:"base,omitempty"`
+	Assertion     string `json:"assertion,omitempty"`
+	Precision     string `json:"precision,omitempty"`
+	FloatEncoding string `json:"floatEncoding,omitempty"`
+	MediaType     string `json:"mediaType,omitempty"`
+}
+
+// ProfileProperty holds the value and units of a device resource.
+type ProfileProperty struct {
+	Value PropertyValue `json:"value"`
+	Units Units         `json:"units"`
+}
+
+// DeviceResource is a value a device of a profile reads or writes.
+type DeviceResource struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Tag         string                 `json:"tag,omitempty"`
+	Properties  ProfileProperty        `json:"properties"`
+	Attributes  map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// ResourceOperation is a step of a profile resource.
+type ResourceOperation struct {
+	Index     string            `json:"index,omitempty"`
+	Operation string            `json:"operation,omitempty"`
+	Object    string            `json:"object,omitempty"`
+	Parameter string            `json:"parameter,omitempty"`
+	Resource  string            `json:"resource,omitempty"`
+	Secondary []string          `json:"secondary,omitempty"`
+	Mappings  map[string]string `json:"mappings,omitempty"`
+}
+
+// ProfileResource is a command of a profile as a list of operations on its
+// device resources.
+type ProfileResource struct {
+	Name string              `json:"name"`
+	Get  []ResourceOperation `json:"get,omitempty"`
+	Set  []ResourceOperation `json:"set,omitempty"`
+}
+
+// DeviceProfile describes the resources and commands of a kind of device.
+type DeviceProfile struct {
+	Timestamps
+	Id              string            `json:"id,omitempty"`
+	Name            string            `json:"name,omitempty"`
+	Description     string            `json:"description,omitempty"`
+	Manufacturer    string            `json:"manufacturer,omitempty"`
+	Model           string            `json:"model,omitempty"`
+	Labels          []string          `json:"labels,omitempty"`
+	DeviceResources []DeviceResource  `json:"deviceResources,omitempty"`
+	Resources       []ProfileResource `json:"resources,omitempty"`
+	Commands        []Command         `json:"commands,omitempty"`
+}
+
+// AutoEvent reads a resource of a device periodically.
+type AutoEvent struct {
+	Frequency string `json:"frequency,omitempty"`
+	OnChange  bool   `json:"onChange"`
+	Resource  string `json:"resource,omitempty"`
+}
+
+// Device is a device of the metadata service. Devices are added with only
+// the names of their profile and service.
+type Device struct {
+	Timestamps
+	Id             string                       `json:"id,omitempty"`
+	Name           string                       `json:"name,omitempty"`
+	Description    string                       `json:"description"`
+	Labels         []string                     `json:"labels"`
+	AdminState     string                       `json:"adminState,omitempty"`
+	OperatingState string                       `json:"operatingState,omitempty"`
+	LastConnected  int64                        `json:"lastConnected,omitempty"`
+	LastReported   int64                        `json:"lastReported,omitempty"`
+	Protocols      map[string]map[string]string `json:"protocols,omitempty"`
+	AutoEvents     []AutoEvent                  `json:"autoEvents,omitempty"`
+	Profile        DeviceProfile                `json:"profile"`
+	Service        DeviceService                `json:"service"`
+	Addressable    *Addressable                 `json:"addressable,omitempty"`
+}
+
+// ScheduleEvent is a schedule event of the metadata service.
+type ScheduleEvent struct {
+	Timestamps
+	Id          string      `json:"id,omitempty"`
+	Name        string      `json:"name,omitempty"`
+	Schedule    string      `json:"schedule,omitempty"`
+	Parameters  string      `json:"parameters,omitempty"`
+	Service     string      `json:"service,omitempty"`
+	Addressable Addressable `json:"addressable"`
+}
+
+// Response is a possible response of a command.
+type Response struct {
+	Code           string   `json:"code,omitempty"`
+	Description    string   `json:"description,omitempty"`
+	ExpectedValues []string `json:"expectedValues,omitempty"`
+}
+
+// CommandAction is the get or put part of a device command.
+type CommandAction struct {
+	Path           string     `json:"path"`
+	URL            string     `json:"url,omitempty"`
+	Responses      []Response `json:"responses,omitempty"`
+	ParameterNames []string   `json:"parameterNames,omitempty"`
+}
+
+// Command is a command of a device, read with get and set with put.
+type Command struct {
+	Timestamps
+	Id   string        `json:"id"`
+	Name string        `json:"name"`
+	Get  CommandAction `json:"get"`
+	Put  CommandAction `json:"put"`
+}
+
+// DeviceCommands are the commands of a device in the command service.
+type DeviceCommands struct {
+	Id       string    `json:"id"`
+	Name     string    `json:"name"`
+	Commands []Command `json:"commands"`
+}
+
+// Reading is a value read from a device.
+type Reading struct {
+	Timestamps
+	Id     string `json:"id"`
+	Pushed int64  `json:"pushed,omitempty"`
+	Device string `json:"device"`
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+}
+
+// Event is the set of readings taken from a device at once, as returned by
+// a get command.
+type Event struct {
+	Timestamps
+	Id       string    `json:"id,omitempty"`
+	Pushed   int64     `json:"pushed,omitempty"`
+	Device   string    `json:"device"`
+	Readings []Reading `json:"readings"`
+}
+
+// ValueDescriptor describes the values of the readings of a name.
+type ValueDescriptor struct {
+	Timestamps
+	Id           string      `json:"id"`
+	Name         string      `json:"name"`
+	Description  string      `json:"description,omitempty"`
+	Min          interface{} `json:"min,omitempty"`
+	Max          interface{} `json:"max,omitempty"`
+	DefaultValue interface{} `json:"defaultValue,omitempty"`
+	Type         string      `json:"type,omitempty"`
+	UomLabel     string      `json:"uomLabel,omitempty"`
+	Formatting   string      `json:"formatting,omitempty"`
+	Labels       []string    `json:"labels,omitempty"`
+}
+
+// LogEntry is an entry of the logging service.
+type LogEntry struct {
+	OriginService string   `json:"originService"`
+	LogLevel      string   `json:"logLevel"`
+	Labels        []string `json:"labels,omitempty"`
+	Message       string   `json:"message"`
+	Created       int64    `json:"created"`
+}
+
+// Interval is a schedule of the scheduler service.
+type Interval struct {
+	Timestamps
+	Id        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	Frequency string `json:"frequency"`
+	Cron      string `json:"cron,omitempty"`
+	RunOnce   bool   `json:"runOnce"`
+}
+
+// IntervalAction is an action run on an interval, a schedule event.
+type IntervalAction struct {
+	Timestamps
+	Id         string `json:"id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Interval   string `json:"interval"`
+	Parameters string `json:"parameters"`
+	Target     string `json:"target"`
+	Protocol   string `json:"protocol,omitempty"`
+	HTTPMethod string `json:"httpMethod,omitempty"`
+	Address    string `json:"address"`
+	Port       int    `json:"port,omitempty"`
+	Path       string `json:"path"`
+	Publisher  string `json:"publisher,omitempty"`
+	Topic      string `json:"topic,omitempty"`
+	User       string `json:"user,omitempty"`
+	Password   string `json:"password,omitempty"`
+}
+
+// Notification is a notification of the notifications service.
+type Notification struct {
+	Timestamps
+	Id          string   `json:"id,omitempty"`
+	Slug        string   `json:"slug"`
+	Sender      string   `json:"sender"`
+	Category    string   `json:"category"`
+	Severity    string   `json:"severity"`
+	Content     string   `json:"content"`
+	Description string   `json:"description,omitempty"`
+	Status      string   `json:"status,omitempty"`
+	Labels      []string `json:"labels"`
+	ContentType string   `json:"contenttype,omitempty"`
+}
+
+// Channel is how a subscription receives notifications.
+type Channel struct {
+	Type          string   `json:"type,omitempty"` // REST or EMAIL
+	MailAddresses []string `json:"mailAddresses,omitempty"`
+	Url           string   `json:"url,omitempty"`
+}
+
+// Subscription subscribes a receiver to notifications.
+type Subscription struct {
+	Timestamps
+	Id                   string    `json:"id,omitempty"`
+	Slug                 string    `json:"slug"`
+	Receiver             string    `json:"receiver"`
+	Description          string    `json:"description,omitempty"`
+	SubscribedCategories []string  `json:"subscribedCategories,omitempty"`
+	SubscribedLabels     []string  `json:"subscribedLabels,omitempty"`
+	Channels             []Channel `json:"channels"`
+}
+
+// TransmissionRecord is an attempt to send a transmission.
+type TransmissionRecord struct {
+	Status   string `json:"status,omitempty"`
+	Response string `json:"response,omitempty"`
+	Sent     int64  `json:"sent,omitempty"`
+}
+
+// Transmission is a notification sent to a receiver.
+type Transmission struct {
+	Timestamps
+	Id           string               `json:"id"`
+	Notification Notification         `json:"notification"`
+	Receiver     string               `json:"receiver"`
+	Channel      Channel              `json:"channel"`
+	Status       string               `json:"status"`
+	ResendCount  int                  `json:"resendcount"`
+	Records      []TransmissionRecord `json:"records,omitempty"`
+}
+
+// Encryption is how an export encrypts the readings it sends.
+type Encryption struct {
+	EncryptionAlgorithm string `json:"encryptionAlgorithm"`
+	EncryptionKey       string `json:"encryptionKey,omitempty"`
+	InitializingVector  string `json:"initializingVector,omitempty"`
+}
+
+// Filter selects the readings an export sends.
+type Filter struct {
+	DeviceIdentifiers          []string `json:"deviceIdentifiers"`
+	ValueDescriptorIdentifiers []string `json:"valueDescriptorIdentifiers"`
+}
+
+// Registration is an export of readings by the export service.
+type Registration struct {
+	Timestamps
+	Id          string      `json:"id,omitempty"`
+	Name        string      `json:"name,omitempty"`
+	Addressable Addressable `json:"addressable"`
+	Format      string      `json:"format"`
+	Destination string      `json:"destination"`
+	Compression string      `json:"compression"`
+	Encryption  Encryption  `json:"encryption"`
+	Filter      Filter      `json:"filter"`
+	Enable      bool        `json:"enable"`
+}
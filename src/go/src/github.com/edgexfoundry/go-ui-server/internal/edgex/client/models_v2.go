@@ -0,0 +1,606 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The objects of the v2 APIs, as far as the UI uses them, and how they map
+// to and from the v1 models.
+
+// timesV2 are the times, in milliseconds, the v2 APIs keep of the objects
+// they store.
+type timesV2 struct {
+	Created  int64 `json:"created,omitempty"`
+	Modified int64 `json:"modified,omitempty"`
+}
+
+func (t timesV2) model() Timestamps {
+	return Timestamps{Created: t.Created, Modified: t.Modified}
+}
+
+// originTimes returns the times of a reading or event taken at origin, in
+// nanoseconds, with its creation time in milliseconds as in v1.
+func originTimes(origin int64) Timestamps {
+	return Timestamps{Created: origin / int64(time.Millisecond), Origin: origin}
+}
+
+// The operating states of devices, which v2 renamed
+var operatingStatesV2 = map[string]string{"ENABLED": "UP", "DISABLED": "DOWN"}
+
+func operatingStateV2(state string) string {
+	if v2, ok := operatingStatesV2[state]; ok {
+		return v2
+	}
+	return state
+}
+
+func operatingStateV1(state string) string {
+	for v1, v2 := range operatingStatesV2 {
+		if v2 == state {
+			return v1
+		}
+	}
+	return state
+}
+
+// addressV2 is the address of a subscription channel or of the target of an
+// interval action. EMAIL addresses only have recipients.
+type addressV2 struct {
+	Type       string   `json:"type"`
+	Host       string   `json:"host,omitempty"`
+	Port       int      `json:"port,omitempty"`
+	Path       string   `json:"path,omitempty"`
+	HTTPMethod string   `json:"httpMethod,omitempty"`
+	Publisher  string   `json:"publisher,omitempty"`
+	Topic      string   `json:"topic,omitempty"`
+	Recipients []string `json:"recipients,omitempty"`
+}
+
+func channelV2(channel Channel) addressV2 {
+	if channel.Type == "EMAIL" {
+		return addressV2{Type: channel.Type, Recipients: channel.MailAddresses}
+	}
+	address := addressV2{Type: "REST", HTTPMethod: "POST"}
+	if u, err := url.Parse(channel.Url); err == nil {
+		address.Host = u.Hostname()
+		address.Port, _ = strconv.Atoi(u.Port())
+		if address.Port == 0 && u.Scheme == "https" {
+			address.Port = 443
+		} else if address.Port == 0 {
+			address.Port = 80
+		}
+		address.Path = u.RequestURI()
+	}
+	return address
+}
+
+func (a addressV2) channel() Channel {
+	if a.Type == "EMAIL" {
+		return Channel{Type: a.Type, MailAddresses: a.Recipients}
+	}
+	return Channel{Type: a.Type, Url: "http://" + a.Host + ":" + strconv.Itoa(a.Port) + a.Path}
+}
+
+// addressable returns the address baseURL of the service name.
+func addressable(name string, baseURL string) *Addressable {
+	u, err := url.Parse(baseURL)
+	if err != nil || baseURL == "" {
+		return nil
+	}
+	port, _ := strconv.Atoi(u.Port())
+	return &Addressable{
+		Id:       name,
+		Name:     name,
+		Protocol: strings.ToUpper(u.Scheme),
+		Address:  u.Hostname(),
+		Port:     port,
+		Path:     u.Path,
+		BaseURL:  baseURL,
+		URL:      baseURL,
+	}
+}
+
+type autoEventV2 struct {
+	Interval   string `json:"interval"`
+	OnChange   bool   `json:"onChange"`
+	SourceName string `json:"sourceName"`
+}
+
+type deviceV2 struct {
+	timesV2
+	Id             string                       `json:"id,omitempty"`
+	Name           string                       `json:"name"`
+	Description    string                       `json:"description,omitempty"`
+	AdminState     string                       `json:"adminState,omitempty"`
+	OperatingState string                       `json:"operatingState,omitempty"`
+	LastConnected  int64                        `json:"lastConnected,omitempty"`
+	LastReported   int64                        `json:"lastReported,omitempty"`
+	Labels         []string                     `json:"labels,omitempty"`
+	ServiceName    string                       `json:"serviceName,omitempty"`
+	ProfileName    string                       `json:"profileName,omitempty"`
+	AutoEvents     []autoEventV2                `json:"autoEvents,omitempty"`
+	Protocols      map[string]map[string]string `json:"protocols,omitempty"`
+}
+
+func newDeviceV2(device Device) deviceV2 {
+	result := deviceV2{
+		Name:           device.Name,
+		Description:    device.Description,
+		AdminState:     device.AdminState,
+		OperatingState: operatingStateV2(device.OperatingState),
+		Labels:         device.Labels,
+		ServiceName:    device.Service.Name,
+		ProfileName:    device.Profile.Name,
+		Protocols:      device.Protocols,
+	}
+	for _, event := range device.AutoEvents {
+		result.AutoEvents = append(result.AutoEvents, autoEventV2{
+			Interval:   event.Frequency,
+			OnChange:   event.OnChange,
+			SourceName: event.Resource,
+		})
+	}
+	return result
+}
+
+func (d deviceV2) model() Device {
+	device := Device{
+		Timestamps:     d.timesV2.model(),
+		Id:             d.Name,
+		Name:           d.Name,
+		Description:    d.Description,
+		Labels:         d.Labels,
+		AdminState:     d.AdminState,
+		OperatingState: operatingStateV1(d.OperatingState),
+		LastConnected:  d.LastConnected,
+		LastReported:   d.LastReported,
+		Protocols:      d.Protocols,
+		Profile:        DeviceProfile{Id: d.ProfileName, Name: d.ProfileName},
+		Service:        DeviceService{Id: d.ServiceName, Name: d.ServiceName},
+	}
+	for _, event := range d.AutoEvents {
+		device.AutoEvents = append(device.AutoEvents, AutoEvent{
+			Frequency: event.Interval,
+			OnChange:  event.OnChange,
+			Resource:  event.SourceName,
+		})
+	}
+	return device
+}
+
+type deviceServiceV2 struct {
+	timesV2
+	Id            string   `json:"id,omitempty"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description,omitempty"`
+	LastConnected int64    `json:"lastConnected,omitempty"`
+	LastReported  int64    `json:"lastReported,omitempty"`
+	Labels        []string `json:"labels,omitempty"`
+	BaseAddress   string   `json:"baseAddress"`
+	AdminState    string   `json:"adminState"`
+}
+
+func (s deviceServiceV2) model() DeviceService {
+	return DeviceService{
+		Timestamps:    s.timesV2.model(),
+		Id:            s.Name,
+		Name:          s.Name,
+		Description:   s.Description,
+		Labels:        s.Labels,
+		AdminState:    s.AdminState,
+		LastConnected: s.LastConnected,
+		LastReported:  s.LastReported,
+		Addressable:   addressable(s.Name, s.BaseAddress),
+	}
+}
+
+// The device profiles are also written as YAML, as the v1 API returned them.
+
+type resourcePropertiesV2 struct {
+	ValueType    string `json:"valueType" yaml:"valueType"`
+	ReadWrite    string `json:"readWrite" yaml:"readWrite"`
+	Units        string `json:"units,omitempty" yaml:"units,omitempty"`
+	Minimum      string `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	Maximum      string `json:"maximum,omitempty" yaml:"maximum,omitempty"`
+	DefaultValue string `json:"defaultValue,omitempty" yaml:"defaultValue,omitempty"`
+	Mask         string `json:"mask,omitempty" yaml:"mask,omitempty"`
+	Shift        string `json:"shift,omitempty" yaml:"shift,omitempty"`
+	Scale        string `json:"scale,omitempty" yaml:"scale,omitempty"`
+	Offset       string `json:"offset,omitempty" yaml:"offset,omitempty"`
+	Base         string `json:"base,omitempty" yaml:"base,omitempty"`
+	Assertion    string `json:"assertion,omitempty" yaml:"assertion,omitempty"`
+	MediaType    string `json:"mediaType,omitempty" yaml:"mediaType,omitempty"`
+}
+
+type deviceResourceV2 struct {
+	Name        string                 `json:"name" yaml:"name"`
+	Description string                 `json:"description,omitempty" yaml:"description,omitempty"`
+	IsHidden    bool                   `json:"isHidden" yaml:"isHidden"`
+	Tag         string                 `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Properties  resourcePropertiesV2   `json:"properties" yaml:"properties"`
+	Attributes  map[string]interface{} `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+type resourceOperationV2 struct {
+	DeviceResource string            `json:"deviceResource" yaml:"deviceResource"`
+	DefaultValue   string            `json:"defaultValue,omitempty" yaml:"defaultValue,omitempty"`
+	Mappings       map[string]string `json:"mappings,omitempty" yaml:"mappings,omitempty"`
+}
+
+type deviceCommandV2 struct {
+	Name               string                `json:"name" yaml:"name"`
+	IsHidden           bool                  `json:"isHidden" yaml:"isHidden"`
+	ReadWrite          string                `json:"readWrite" yaml:"readWrite"`
+	ResourceOperations []resourceOperationV2 `json:"resourceOperations" yaml:"resourceOperations"`
+}
+
+type deviceProfileV2 struct {
+	timesV2         `yaml:"-"`
+	Id              string             `json:"id,omitempty" yaml:"-"`
+	Name            string             `json:"name" yaml:"name"`
+	Manufacturer    string             `json:"manufacturer,omitempty" yaml:"manufacturer,omitempty"`
+	Description     string             `json:"description,omitempty" yaml:"description,omitempty"`
+	Model           string             `json:"model,omitempty" yaml:"model,omitempty"`
+	Labels          []string           `json:"labels,omitempty" yaml:"labels,omitempty"`
+	DeviceResources []deviceResourceV2 `json:"deviceResources" yaml:"deviceResources"`
+	DeviceCommands  []deviceCommandV2  `json:"deviceCommands,omitempty" yaml:"deviceCommands,omitempty"`
+}
+
+func (p deviceProfileV2) model() DeviceProfile {
+	profile := DeviceProfile{
+		Timestamps:   p.timesV2.model(),
+		Id:           p.Name,
+		Name:         p.Name,
+		Description:  p.Description,
+		Manufacturer: p.Manufacturer,
+		Model:        p.Model,
+		Labels:       p.Labels,
+	}
+	for _, resource := range p.DeviceResources {
+		properties := resource.Properties
+		profile.DeviceResources = append(profile.DeviceResources, DeviceResource{
+			Name:        resource.Name,
+			Description: resource.Description,
+			Tag:         resource.Tag,
+			Properties: ProfileProperty{
+				Value: PropertyValue{
+					Type:         properties.ValueType,
+					ReadWrite:    properties.ReadWrite,
+					Minimum:      properties.Minimum,
+					Maximum:      properties.Maximum,
+					DefaultValue: properties.DefaultValue,
+					Mask:         properties.Mask,
+					Shift:        properties.Shift,
+					Scale:        properties.Scale,
+					Offset:       properties.Offset,
+					Base:         properties.Base,
+					Assertion:    properties.Assertion,
+					MediaType:    properties.MediaType,
+				},
+				Units: Units{Type: "String", ReadWrite: "R", DefaultValue: properties.Units},
+			},
+			Attributes: resource.Attributes,
+		})
+	}
+	for _, command := range p.DeviceCommands {
+		var operations []ResourceOperation
+		for i, operation := range command.ResourceOperations {
+			operations = append(operations, ResourceOperation{
+				Index:     strconv.Itoa(i + 1),
+				Object:    operation.DeviceResource,
+				Parameter: operation.DefaultValue,
+				Mappings:  operation.Mappings,
+			})
+		}
+		resource := ProfileResource{Name: command.Name}
+		if strings.Contains(command.ReadWrite, "R") {
+			resource.Get = operations
+		}
+		if strings.Contains(command.ReadWrite, "W") {
+			resource.Set = operations
+		}
+		profile.Resources = append(profile.Resources, resource)
+	}
+	return profile
+}
+
+type commandParameterV2 struct {
+	ResourceName string `json:"resourceName"`
+	ValueType    string `json:"valueType"`
+}
+
+type coreCommandV2 struct {
+	Name       string               `json:"name"`
+	Get        bool                 `json:"get"`
+	Set        bool                 `json:"set"`
+	Path       string               `json:"path"`
+	URL        string               `json:"url"`
+	Parameters []commandParameterV2 `json:"parameters"`
+}
+
+type deviceCoreCommandV2 struct {
+	DeviceName   string          `json:"deviceName"`
+	ProfileName  string          `json:"profileName"`
+	CoreCommands []coreCommandV2 `json:"coreCommands"`
+}
+
+func (d deviceCoreCommandV2) model() DeviceCommands {
+	device := DeviceCommands{Id: d.DeviceName, Name: d.DeviceName}
+	for _, command := range d.CoreCommands {
+		var names []string
+		for _, parameter := range command.Parameters {
+			names = append(names, parameter.ResourceName)
+		}
+		cmd := Command{Id: command.Name, Name: command.Name}
+		if command.Get {
+			cmd.Get = CommandAction{
+				Path:      command.Path,
+				URL:       command.URL,
+				Responses: []Response{{Code: "200", ExpectedValues: names}},
+			}
+		}
+		if command.Set {
+			cmd.Put = CommandAction{Path: command.Path, URL: command.URL, ParameterNames: names}
+		}
+		device.Commands = append(device.Commands, cmd)
+	}
+	return device
+}
+
+type readingV2 struct {
+	Id           string `json:"id"`
+	Origin       int64  `json:"origin"`
+	DeviceName   string `json:"deviceName"`
+	ResourceName string `json:"resourceName"`
+	ProfileName  string `json:"profileName"`
+	ValueType    string `json:"valueType"`
+	Value        string `json:"value,omitempty"`
+	MediaType    string `json:"mediaType,omitempty"`
+}
+
+func (r readingV2) model() Reading {
+	return Reading{
+		Timestamps: originTimes(r.Origin),
+		Id:         r.Id,
+		Device:     r.DeviceName,
+		Name:       r.ResourceName,
+		Value:      r.Value,
+	}
+}
+
+type eventV2 struct {
+	Id          string      `json:"id"`
+	DeviceName  string      `json:"deviceName"`
+	ProfileName string      `json:"profileName"`
+	SourceName  string      `json:"sourceName"`
+	Origin      int64       `json:"origin"`
+	Readings    []readingV2 `json:"readings"`
+}
+
+func (e eventV2) model() Event {
+	event := Event{Timestamps: originTimes(e.Origin), Id: e.Id, Device: e.DeviceName}
+	for _, reading := range e.Readings {
+		event.Readings = append(event.Readings, reading.model())
+	}
+	return event
+}
+
+type notificationV2 struct {
+	timesV2
+	Id          string   `json:"id,omitempty"`
+	Category    string   `json:"category,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	Content     string   `json:"content"`
+	ContentType string   `json:"contentType,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Sender      string   `json:"sender"`
+	Severity    string   `json:"severity"`
+	Status      string   `json:"status,omitempty"`
+}
+
+func newNotificationV2(notification Notification) notificationV2 {
+	return notificationV2{
+		Category:    notification.Category,
+		Labels:      notification.Labels,
+		Content:     notification.Content,
+		ContentType: notification.ContentType,
+		Description: notification.Description,
+		Sender:      notification.Sender,
+		Severity:    notification.Severity,
+	}
+}
+
+// model returns the notification with its id as slug, which v2 dropped.
+func (n notificationV2) model() Notification {
+	return Notification{
+		Timestamps:  n.timesV2.model(),
+		Id:          n.Id,
+		Slug:        n.Id,
+		Sender:      n.Sender,
+		Category:    n.Category,
+		Severity:    n.Severity,
+		Content:     n.Content,
+		Description: n.Description,
+		Status:      n.Status,
+		Labels:      n.Labels,
+		ContentType: n.ContentType,
+	}
+}
+
+type subscriptionV2 struct {
+	timesV2
+	Id          string      `json:"id,omitempty"`
+	Name        string      `json:"name"`
+	Channels    []addressV2 `json:"channels,omitempty"`
+	Receiver    string      `json:"receiver,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Categories  []string    `json:"categories,omitempty"`
+	Labels      []string    `json:"labels,omitempty"`
+	AdminState  string      `json:"adminState,omitempty"`
+}
+
+func newSubscriptionV2(subscription Subscription) subscriptionV2 {
+	result := subscriptionV2{
+		Name:        subscription.Slug,
+		Receiver:    subscription.Receiver,
+		Description: subscription.Description,
+		Categories:  subscription.SubscribedCategories,
+		Labels:      subscription.SubscribedLabels,
+	}
+	for _, channel := range subscription.Channels {
+		result.Channels = append(result.Channels, channelV2(channel))
+	}
+	return result
+}
+
+// model returns the subscription with its name as id and slug.
+func (s subscriptionV2) model() Subscription {
+	subscription := Subscription{
+		Timestamps:           s.timesV2.model(),
+		Id:                   s.Name,
+		Slug:                 s.Name,
+		Receiver:             s.Receiver,
+		Description:          s.Description,
+		SubscribedCategories: s.Categories,
+		SubscribedLabels:     s.Labels,
+	}
+	for _, channel := range s.Channels {
+		subscription.Channels = append(subscription.Channels, channel.channel())
+	}
+	return subscription
+}
+
+type transmissionV2 struct {
+	timesV2
+	Id               string               `json:"id"`
+	SubscriptionName string               `json:"subscriptionName"`
+	Channel          addressV2            `json:"channel"`
+	NotificationId   string               `json:"notificationId"`
+	Status           string               `json:"status"`
+	ResendCount      int                  `json:"resendCount"`
+	Records          []TransmissionRecord `json:"records,omitempty"`
+}
+
+// model returns the transmission with the subscription as receiver and the
+// notification by id only.
+func (t transmissionV2) model() Transmission {
+	return Transmission{
+		Timestamps:   t.timesV2.model(),
+		Id:           t.Id,
+		Notification: Notification{Id: t.NotificationId, Slug: t.NotificationId},
+		Receiver:     t.SubscriptionName,
+		Channel:      t.Channel.channel(),
+		Status:       t.Status,
+		ResendCount:  t.ResendCount,
+		Records:      t.Records,
+	}
+}
+
+type intervalV2 struct {
+	timesV2
+	Id       string `json:"id,omitempty"`
+	Name     string `json:"name"`
+	Start    string `json:"start,omitempty"`
+	End      string `json:"end,omitempty"`
+	Interval string `json:"interval"`
+}
+
+func newIntervalV2(interval Interval) intervalV2 {
+	return intervalV2{
+		Name:     interval.Name,
+		Start:    interval.Start,
+		End:      interval.End,
+		Interval: durationV2(interval.Frequency),
+	}
+}
+
+func (i intervalV2) model() Interval {
+	return Interval{
+		Timestamps: i.timesV2.model(),
+		Id:         i.Name,
+		Name:       i.Name,
+		Start:      i.Start,
+		End:        i.End,
+		Frequency:  i.Interval,
+	}
+}
+
+// durationV2 returns the ISO 8601 duration frequency of v1, such as PT15S,
+// as a duration of v2, such as 15s. Other frequencies are returned as they
+// are.
+func durationV2(frequency string) string {
+	upper := strings.ToUpper(frequency)
+	if !strings.HasPrefix(upper, "PT") || len(upper) == 2 {
+		return frequency
+	}
+	duration, err := time.ParseDuration(strings.ToLower(upper[2:]))
+	if err != nil {
+		return frequency
+	}
+	return duration.String()
+}
+
+type intervalActionV2 struct {
+	timesV2
+	Id           string    `json:"id,omitempty"`
+	Name         string    `json:"name"`
+	IntervalName string    `json:"intervalName"`
+	Address      addressV2 `json:"address"`
+	Content      string    `json:"content,omitempty"`
+	ContentType  string    `json:"contentType,omitempty"`
+	AdminState   string    `json:"adminState,omitempty"`
+}
+
+func newIntervalActionV2(action IntervalAction) intervalActionV2 {
+	address := addressV2{
+		Type:       "REST",
+		Host:       action.Address,
+		Port:       action.Port,
+		Path:       action.Path,
+		HTTPMethod: action.HTTPMethod,
+	}
+	if strings.EqualFold(action.Protocol, "MQTT") {
+		address = addressV2{
+			Type:      "MQTT",
+			Host:      action.Address,
+			Port:      action.Port,
+			Publisher: action.Publisher,
+			Topic:     action.Topic,
+		}
+	}
+	return intervalActionV2{
+		Name:         action.Name,
+		IntervalName: action.Interval,
+		Address:      address,
+		Content:      action.Parameters,
+		AdminState:   "UNLOCKED",
+	}
+}
+
+func (a intervalActionV2) model() IntervalAction {
+	protocol := "HTTP"
+	if a.Address.Type == "MQTT" {
+		protocol = "MQTT"
+	}
+	return IntervalAction{
+		Timestamps: a.timesV2.model(),
+		Id:         a.Name,
+		Name:       a.Name,
+		Interval:   a.IntervalName,
+		Parameters: a.Content,
+		Protocol:   protocol,
+		HTTPMethod: a.Address.HTTPMethod,
+		Address:    a.Address.Host,
+		Port:       a.Address.Port,
+		Path:       a.Address.Path,
+		Publisher:  a.Address.Publisher,
+		Topic:      a.Address.Topic,
+	}
+}
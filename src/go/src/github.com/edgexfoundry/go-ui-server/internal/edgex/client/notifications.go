@@ -0,0 +1,203 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"net/url"
+	"sort"
+)
+
+// Notifications returns the notifications created from from to to, in
+// milliseconds, oldest first.
+func (c *Client) Notifications(from int64, to int64) ([]Notification, error) {
+	if c.v2(ServiceNotifications) {
+		return c.notificationsV2(from, to)
+	}
+	var notifications []Notification
+	ids := make(map[string]bool)
+	err := byTime(from, func(from int64) (int, int64, error) {
+		var batch []Notification
+		err := c.get(ServiceNotifications, "Notifications", "notification/"+startEnd(from, to, batchSize), &batch)
+		var last int64
+		for _, notification := range batch {
+			last = notification.Created
+			if !ids[notification.Id] {
+				ids[notification.Id] = true
+				notifications = append(notifications, notification)
+			}
+		}
+		return len(batch), last, err
+	})
+	return notifications, err
+}
+
+// AddNotification adds notification and returns its id.
+func (c *Client) AddNotification(notification Notification) (string, error) {
+	if c.v2(ServiceNotifications) {
+		return c.postV2(ServiceNotifications, "AddNotification", "notification", "notification",
+			newNotificationV2(notification))
+	}
+	return c.post(ServiceNotifications, "AddNotification", "notification", notification)
+}
+
+// DeleteNotification deletes the notification slug, its id with the v2 API.
+func (c *Client) DeleteNotification(slug string) error {
+	if c.v2(ServiceNotifications) {
+		return c.delete(ServiceNotifications, "DeleteNotification", "notification/id/"+url.PathEscape(slug))
+	}
+	return c.delete(ServiceNotifications, "DeleteNotification", "notification/slug/"+url.PathEscape(slug))
+}
+
+// Subscriptions returns all subscriptions.
+func (c *Client) Subscriptions() ([]Subscription, error) {
+	if c.v2(ServiceNotifications) {
+		return c.subscriptionsV2()
+	}
+	var subscriptions []Subscription
+	err := c.get(ServiceNotifications, "Subscriptions", "subscription", &subscriptions)
+	return subscriptions, err
+}
+
+// Subscription returns the subscription slug.
+func (c *Client) Subscription(slug string) (Subscription, error) {
+	if c.v2(ServiceNotifications) {
+		return c.subscriptionV2(slug)
+	}
+	var subscription Subscription
+	err := c.get(ServiceNotifications, "Subscription", "subscription/slug/"+url.PathEscape(slug), &subscription)
+	return subscription, err
+}
+
+// AddSubscription adds subscription.
+func (c *Client) AddSubscription(subscription Subscription) error {
+	if c.v2(ServiceNotifications) {
+		added := newSubscriptionV2(subscription)
+		added.AdminState = "UNLOCKED"
+		_, err := c.postV2(ServiceNotifications, "AddSubscription", "subscription", "subscription", added)
+		return err
+	}
+	_, err := c.post(ServiceNotifications, "AddSubscription", "subscription", subscription)
+	return err
+}
+
+// UpdateSubscription changes the subscription with the id of subscription,
+// or with its slug with the v2 API.
+func (c *Client) UpdateSubscription(subscription Subscription) error {
+	if c.v2(ServiceNotifications) {
+		return c.patchV2(ServiceNotifications, "UpdateSubscription", "subscription", "subscription",
+			newSubscriptionV2(subscription))
+	}
+	return c.put(ServiceNotifications, "UpdateSubscription", "subscription", subscription)
+}
+
+// DeleteSubscription deletes the subscription slug.
+func (c *Client) DeleteSubscription(slug string) error {
+	if c.v2(ServiceNotifications) {
+		return c.delete(ServiceNotifications, "DeleteSubscription", "subscription/name/"+url.PathEscape(slug))
+	}
+	return c.delete(ServiceNotifications, "DeleteSubscription", "subscription/slug/"+url.PathEscape(slug))
+}
+
+// Transmissions returns the transmissions created from from to to, in
+// milliseconds, oldest first.
+func (c *Client) Transmissions(from int64, to int64) ([]Transmission, error) {
+	if c.v2(ServiceNotifications) {
+		return c.transmissionsV2("Transmissions", "transmission/"+startEndV2(from, to), from, to)
+	}
+	return c.transmissions("Transmissions", "transmission/", from, to)
+}
+
+// NotificationTransmissions returns the transmissions of the notification
+// slug created from from to to, oldest first.
+func (c *Client) NotificationTransmissions(slug string, from int64, to int64) ([]Transmission, error) {
+	if c.v2(ServiceNotifications) {
+		return c.transmissionsV2("NotificationTransmissions", "transmission/notification/id/"+url.PathEscape(slug), from, to)
+	}
+	return c.transmissions("NotificationTransmissions", "transmission/slug/"+url.PathEscape(slug)+"/", from, to)
+}
+
+// transmissions reads the transmissions created from from to to from path,
+// followed by the time range.
+func (c *Client) transmissions(operation string, path string, from int64, to int64) ([]Transmission, error) {
+	var transmissions []Transmission
+	ids := make(map[string]bool)
+	err := byTime(from, func(from int64) (int, int64, error) {
+		var batch []Transmission
+		err := c.get(ServiceNotifications, operation, path+startEnd(from, to, batchSize), &batch)
+		var last int64
+		for _, transmission := range batch {
+			last = transmission.Created
+			if !ids[transmission.Id] {
+				ids[transmission.Id] = true
+				transmissions = append(transmissions, transmission)
+			}
+		}
+		return len(batch), last, err
+	})
+	return transmissions, err
+}
+
+func (c *Client) notificationsV2(from int64, to int64) ([]Notification, error) {
+	var notifications []Notification
+	path := "notification/" + startEndV2(from, to)
+	err := byOffset(func(offset int) (int, int, error) {
+		var reply struct {
+			TotalCount    int              `json:"totalCount"`
+			Notifications []notificationV2 `json:"notifications"`
+		}
+		err := c.get(ServiceNotifications, "Notifications", page(path, offset), &reply)
+		for _, notification := range reply.Notifications {
+			notifications = append(notifications, notification.model())
+		}
+		return len(reply.Notifications), reply.TotalCount, err
+	})
+	sort.SliceStable(notifications, func(i, j int) bool { return notifications[i].Created < notifications[j].Created })
+	return notifications, err
+}
+
+func (c *Client) subscriptionsV2() ([]Subscription, error) {
+	var subscriptions []Subscription
+	err := byOffset(func(offset int) (int, int, error) {
+		var reply struct {
+			TotalCount    int              `json:"totalCount"`
+			Subscriptions []subscriptionV2 `json:"subscriptions"`
+		}
+		err := c.get(ServiceNotifications, "Subscriptions", page("subscription/all", offset), &reply)
+		for _, subscription := range reply.Subscriptions {
+			subscriptions = append(subscriptions, subscription.model())
+		}
+		return len(reply.Subscriptions), reply.TotalCount, err
+	})
+	return subscriptions, err
+}
+
+func (c *Client) subscriptionV2(name string) (Subscription, error) {
+	var reply struct {
+		Subscription subscriptionV2 `json:"subscription"`
+	}
+	err := c.get(ServiceNotifications, "Subscription", "subscription/name/"+url.PathEscape(name), &reply)
+	return reply.Subscription.model(), err
+}
+
+// transmissionsV2 reads the transmissions from path and keeps those created
+// from from to to, as not all paths select by time.
+func (c *Client) transmissionsV2(operation string, path string, from int64, to int64) ([]Transmission, error) {
+	var transmissions []Transmission
+	err := byOffset(func(offset int) (int, int, error) {
+		var reply struct {
+			TotalCount    int              `json:"totalCount"`
+			Transmissions []transmissionV2 `json:"transmissions"`
+		}
+		err := c.get(ServiceNotifications, operation, page(path, offset), &reply)
+		for _, transmission := range reply.Transmissions {
+			if transmission.Created >= from && transmission.Created <= to {
+				transmissions = append(transmissions, transmission.model())
+			}
+		}
+		return len(reply.Transmissions), reply.TotalCount, err
+	})
+	sort.SliceStable(transmissions, func(i, j int) bool { return transmissions[i].Created < transmissions[j].Created })
+	return transmissions, err
+}
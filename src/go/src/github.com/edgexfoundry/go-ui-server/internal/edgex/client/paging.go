@@ -0,0 +1,75 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Lists are read in up to maxRequests batches of batchSize entries.
+const (
+	batchSize   = 100
+	maxRequests = 100
+)
+
+// byTime reads the entries created from from with page, which reads up to
+// batchSize entries created from the time it is given and returns how many
+// it got and the creation time of the last one. The v1 APIs only select by
+// time, so each batch starts at the creation time of the last entry before
+// and page has to skip the entries it read already.
+func byTime(from int64, page func(from int64) (int, int64, error)) error {
+	for i := 0; i < maxRequests; i++ {
+		n, last, err := page(from)
+		if err != nil || n < batchSize {
+			return err
+		}
+		from = last
+	}
+	return nil
+}
+
+// byOffset reads entries with page, which reads up to batchSize entries
+// from an offset and returns how many it got and how many there are in
+// total, as the v2 APIs page their lists.
+func byOffset(page func(offset int) (int, int, error)) error {
+	offset := 0
+	for i := 0; i < maxRequests; i++ {
+		n, total, err := page(offset)
+		if err != nil || n < batchSize {
+			return err
+		}
+		offset += n
+		if total > 0 && offset >= total {
+			return nil
+		}
+	}
+	return nil
+}
+
+// timeRange returns the path of a v1 time range of at most limit entries.
+func timeRange(from int64, to int64, limit int) string {
+	return strconv.FormatInt(from, 10) + "/" + strconv.FormatInt(to, 10) + "/" + strconv.Itoa(limit)
+}
+
+// startEnd returns the path of a v1 time range of at most limit entries
+// given with start and end.
+func startEnd(from int64, to int64, limit int) string {
+	return "start/" + strconv.FormatInt(from, 10) + "/end/" + strconv.FormatInt(to, 10) + "/" + strconv.Itoa(limit)
+}
+
+// startEndV2 returns the path of a v2 time range, paged by offset.
+func startEndV2(from int64, to int64) string {
+	return "start/" + strconv.FormatInt(from, 10) + "/end/" + strconv.FormatInt(to, 10)
+}
+
+// page adds the offset and size of a page of a v2 list to path.
+func page(path string, offset int) string {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + "offset=" + strconv.Itoa(offset) + "&limit=" + strconv.Itoa(batchSize)
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/edgexfoundry/go-ui-server/internal/breaker"
+	"gopkg.in/resty.v1"
+)
+
+// Retry is how reads from a service are retried. Only reads are retried, as
+// they can be repeated without changing anything.
+type Retry struct {
+	// Count is how often a failed read is retried, 0 for never
+	Count int
+	// Wait is the time before the first retry, doubled for each further one
+	// up to MaxWait. The actual wait is between half of it and all of it.
+	Wait    time.Duration
+	MaxWait time.Duration
+}
+
+// backoff returns the time to wait before retry number attempt, counted
+// from 0.
+func (r Retry) backoff(attempt int) time.Duration {
+	wait := r.Wait
+	for i := 0; i < attempt && wait < r.MaxWait; i++ {
+		wait *= 2
+	}
+	if wait > r.MaxWait {
+		wait = r.MaxWait
+	}
+	if wait <= 0 {
+		return 0
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// unavailable tells whether the service, or a proxy in front of it, answered
+// resp as it is unavailable for now.
+func unavailable(resp *resty.Response) bool {
+	switch resp.StatusCode() {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryable tells whether a read that got resp and err may succeed if it is
+// repeated: it could not be sent or timed out, or the service is unavailable
+// for now.
+func retryable(resp *resty.Response, err error) bool {
+	return err != nil || unavailable(resp)
+}
+
+// read gets path of service, retrying as set up for service until it
+// succeeds, the retries are used up, the breaker of service is no longer
+// closed or the context is done. The breaker is told the outcome of the read
+// as a whole, so that its retries count as one failure.
+func (c *Client) read(service string, path string) (*resty.Response, error) {
+	retry := c.services.Retry(service)
+	b := c.services.Breaker(service)
+	return c.send(service, func(r *resty.Request) (*resty.Response, error) {
+		for attempt := 0; ; attempt++ {
+			if attempt > 0 {
+				r = c.request(service)
+			}
+			resp, err := r.Get(c.url(service, path))
+			if attempt >= retry.Count || !retryable(resp, err) || c.ctx.Err() != nil ||
+				b.Status().State != breaker.Closed {
+				return resp, err
+			}
+			timer := time.NewTimer(retry.backoff(attempt))
+			select {
+			case <-timer.C:
+			case <-c.ctx.Done():
+				timer.Stop()
+				return resp, err
+			}
+		}
+	})
+}
@@ -0,0 +1,93 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import "net/url"
+
+// Intervals returns all intervals.
+func (c *Client) Intervals() ([]Interval, error) {
+	if c.v2(ServiceScheduler) {
+		return c.intervalsV2()
+	}
+	var intervals []Interval
+	err := c.get(ServiceScheduler, "Intervals", "interval", &intervals)
+	return intervals, err
+}
+
+// AddInterval adds interval and returns its id.
+func (c *Client) AddInterval(interval Interval) (string, error) {
+	if c.v2(ServiceScheduler) {
+		_, err := c.postV2(ServiceScheduler, "AddInterval", "interval", "interval", newIntervalV2(interval))
+		return interval.Name, err
+	}
+	return c.post(ServiceScheduler, "AddInterval", "interval", interval)
+}
+
+// DeleteInterval deletes the interval id.
+func (c *Client) DeleteInterval(id string) error {
+	if c.v2(ServiceScheduler) {
+		return c.delete(ServiceScheduler, "DeleteInterval", "interval/name/"+url.PathEscape(id))
+	}
+	return c.delete(ServiceScheduler, "DeleteInterval", "interval/"+url.PathEscape(id))
+}
+
+// IntervalActions returns all interval actions.
+func (c *Client) IntervalActions() ([]IntervalAction, error) {
+	if c.v2(ServiceScheduler) {
+		return c.intervalActionsV2()
+	}
+	var actions []IntervalAction
+	err := c.get(ServiceScheduler, "IntervalActions", "intervalaction", &actions)
+	return actions, err
+}
+
+// AddIntervalAction adds action and returns its id.
+func (c *Client) AddIntervalAction(action IntervalAction) (string, error) {
+	if c.v2(ServiceScheduler) {
+		_, err := c.postV2(ServiceScheduler, "AddIntervalAction", "intervalaction", "action", newIntervalActionV2(action))
+		return action.Name, err
+	}
+	return c.post(ServiceScheduler, "AddIntervalAction", "intervalaction", action)
+}
+
+// DeleteIntervalAction deletes the interval action id.
+func (c *Client) DeleteIntervalAction(id string) error {
+	if c.v2(ServiceScheduler) {
+		return c.delete(ServiceScheduler, "DeleteIntervalAction", "intervalaction/name/"+url.PathEscape(id))
+	}
+	return c.delete(ServiceScheduler, "DeleteIntervalAction", "intervalaction/"+url.PathEscape(id))
+}
+
+func (c *Client) intervalsV2() ([]Interval, error) {
+	var intervals []Interval
+	err := byOffset(func(offset int) (int, int, error) {
+		var reply struct {
+			TotalCount int          `json:"totalCount"`
+			Intervals  []intervalV2 `json:"intervals"`
+		}
+		err := c.get(ServiceScheduler, "Intervals", page("interval/all", offset), &reply)
+		for _, interval := range reply.Intervals {
+			intervals = append(intervals, interval.model())
+		}
+		return len(reply.Intervals), reply.TotalCount, err
+	})
+	return intervals, err
+}
+
+func (c *Client) intervalActionsV2() ([]IntervalAction, error) {
+	var actions []IntervalAction
+	err := byOffset(func(offset int) (int, int, error) {
+		var reply struct {
+			TotalCount int                `json:"totalCount"`
+			Actions    []intervalActionV2 `json:"actions"`
+		}
+		err := c.get(ServiceScheduler, "IntervalActions", page("intervalaction/all", offset), &reply)
+		for _, action := range reply.Actions {
+			actions = append(actions, action.model())
+		}
+		return len(reply.Actions), reply.TotalCount, err
+	})
+	return actions, err
+}
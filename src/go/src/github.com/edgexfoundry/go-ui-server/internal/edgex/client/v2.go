@@ -0,0 +1,79 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// The v2 APIs of EdgeX 2.x wrap their replies in envelopes, page lists by
+// offset and limit and address most objects by name. Their objects are read
+// into the v1 models, with the name as the id of the objects addressed by
+// name, so that the callers see no difference. The services EdgeX 2.x
+// dropped, logging and export, and the addressables and schedule events of
+// the metadata service, read as empty and cannot be changed.
+
+// v2 tells whether service is called through the v2 API.
+func (c *Client) v2(service string) bool {
+	return c.services.APIVersion(service) == APIv2
+}
+
+// unsupported returns the error of operation on service, which the v2 API
+// does not have.
+func unsupported(service string, operation string) error {
+	return &Error{Service: service, Operation: operation, Message: "not supported by the " + APIv2 + " API"}
+}
+
+// statusV2 is the status of a request, or of an object of a request, sent
+// with every v2 reply.
+type statusV2 struct {
+	StatusCode int    `json:"statusCode"`
+	Message    string `json:"message,omitempty"`
+	Id         string `json:"id,omitempty"`
+}
+
+// requestV2 wraps object as the only object of an add or update request.
+func requestV2(key string, object interface{}) []map[string]interface{} {
+	return []map[string]interface{}{{"apiVersion": APIv2, key: object}}
+}
+
+// multiStatus returns the status of the only object of the request that got
+// reply, which lists the status of each object with HTTP status 207, as an
+// error if it failed.
+func multiStatus(service string, operation string, reply string) (statusV2, error) {
+	var statuses []statusV2
+	if err := json.Unmarshal([]byte(reply), &statuses); err != nil {
+		return statusV2{}, &Error{Service: service, Operation: operation, Message: "invalid reply: " + err.Error(), Err: err}
+	}
+	if len(statuses) != 1 {
+		return statusV2{}, &Error{Service: service, Operation: operation, Message: fmt.Sprintf("%d replies to one request", len(statuses))}
+	}
+	status := statuses[0]
+	if status.StatusCode < 200 || status.StatusCode > 299 {
+		return status, &Error{Service: service, Operation: operation, Status: status.StatusCode, Message: status.Message}
+	}
+	return status, nil
+}
+
+// postV2 adds object, sent as key, and returns its id.
+func (c *Client) postV2(service string, operation string, path string, key string, object interface{}) (string, error) {
+	reply, err := c.post(service, operation, path, requestV2(key, object))
+	if err != nil {
+		return "", err
+	}
+	status, err := multiStatus(service, operation, reply)
+	return status.Id, err
+}
+
+// patchV2 changes the fields of an object set in object, sent as key.
+func (c *Client) patchV2(service string, operation string, path string, key string, object interface{}) error {
+	reply, err := c.patch(service, operation, path, requestV2(key, object))
+	if err != nil {
+		return err
+	}
+	_, err = multiStatus(service, operation, reply)
+	return err
+}
@@ -1,17 +1,21 @@
 package edgex
 
+import "github.com/edgexfoundry/go-ui-server/internal/edgex/client"
+
 const (
-	ClientData          = "data"
-	ClientMetadata      = "metadata"
-	ClientLogging       = "logging"
-	ClientCommand       = "command"
-	ClientExport        = "export"
-	ClientNotifications = "notifications"
-	ClientScheduler     = "scheduler"
+	ClientData          = client.ServiceData
+	ClientMetadata      = client.ServiceMetadata
+	ClientLogging       = client.ServiceLogging
+	ClientCommand       = client.ServiceCommand
+	ClientExport        = client.ServiceExport
+	ClientNotifications = client.ServiceNotifications
+	ClientScheduler     = client.ServiceScheduler
+
+	APIv1 = client.APIv1
+	APIv2 = client.APIv2
 
-	APIv1Prefix    = "/api/v1"
-	Colon          = ":"
-	HttpScheme     = "http://"
-	HttpProto      = "HTTP"
-	StatusResponse = "pong"
+	Colon           = ":"
+	DefaultProtocol = "http"
+	HttpProto       = "HTTP"
+	StatusResponse  = "pong"
 )
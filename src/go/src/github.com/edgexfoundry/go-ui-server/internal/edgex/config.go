@@ -7,9 +7,15 @@ package edgex
 import (
 	"fmt"
 	"github.com/BurntSushi/toml"
+	"github.com/edgexfoundry/go-ui-server/internal/auth"
+	"github.com/edgexfoundry/go-ui-server/internal/consul"
+	"github.com/edgexfoundry/go-ui-server/internal/fulcro"
+	"github.com/edgexfoundry/go-ui-server/internal/oidc"
+	"github.com/edgexfoundry/go-ui-server/internal/policy"
 	"io/ioutil"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // ClientInfo provides the host and port of another service in the eco-system.
@@ -18,19 +24,96 @@ type ClientInfo struct {
 	Host string
 	// Port defines the port on which to access a given service
 	Port int
-	// Protocol indicates the protocol to use when accessing a given service
+	// Protocol indicates the protocol to use when accessing a given service,
+	// "http" or "https"
 	Protocol string
-	// Timeout specifies a timeout (in milliseconds) for
-	// processing REST calls from other services.
-	// Not currently used.
-	Timeout int
+	// APIVersion is the version of the REST API of the service, "v1" for
+	// EdgeX 1.x, the default, or "v2" for EdgeX 2.x
+	APIVersion string
+	// Timeout is the time in milliseconds a request to the service may take,
+	// and ConnectTimeout the time connecting to it may take, 10000 and 3000
+	// if not set
+	Timeout        int
+	ConnectTimeout int
+	// Retries is how often a failed read from the service is retried, none
+	// if not set. The first retry waits RetryWait milliseconds, each further
+	// one twice as long up to RetryMaxWait, less a random jitter of up to
+	// half the time; 200 and 2000 if not set.
+	Retries      int
+	RetryWait    int
+	RetryMaxWait int
+	// CAFile is a PEM bundle of the CAs trusted for an https service, the
+	// system roots if not set
+	CAFile string
+	// CertFile and KeyFile hold a client certificate presented to the service
+	CertFile string
+	KeyFile  string
+	// InsecureSkipVerify accepts any server certificate. For testing only.
+	InsecureSkipVerify bool
+	// Token is sent as bearer token (e.g. a JWT) with every request. TokenFile
+	// names a file to read it from instead.
+	Token     string
+	TokenFile string
 }
 
 type Config struct {
-	// Port defines the port on which the web server should listen
+	// Port defines the port on which the web server should listen, the
+	// remaining settings whether and how it serves HTTPS, the security
+	// headers and allowed origins of the browser client and the path and
+	// assets of the web client
 	Server struct {
 		Port int
+		fulcro.TLSConfig
+		fulcro.SecurityConfig
+		fulcro.WebConfig
 	}
+	// Session defines, in minutes, how long a login session may stay idle
+	// and how long it may last in total
+	Session struct {
+		IdleTimeout     int
+		AbsoluteTimeout int
+	}
+	// Password sets the rules for new passwords and how they are hashed
+	Password struct {
+		auth.PasswordPolicy
+		auth.HashConfig
+	}
+	// Login throttles failed logins. Durations are in seconds, except for
+	// LockoutDuration and GlobalWindow which are in minutes
+	Login struct {
+		MaxFailures       int
+		LockoutDuration   int
+		BackoffBase       int
+		BackoffMax        int
+		GlobalMaxFailures int
+		GlobalWindow      int
+		// SecurityLog is the file recording logins, stderr if not set
+		SecurityLog string
+	}
+	// OIDC enables logging in through an OpenID Connect provider
+	OIDC oidc.Config
+	// Audit names the file recording every mutation, none are recorded if
+	// it is not set
+	Audit struct {
+		File string
+	}
+	// Endpoints names the file keeping the endpoints saved from the UI, they
+	// are lost on restart if it is not set
+	Endpoints struct {
+		StateFile string
+	}
+	// CommandPolicy decides which device commands may be issued and how
+	// often
+	CommandPolicy policy.Config
+	// CircuitBreaker stops calling a service after Failures failed calls in
+	// a row, failing fast for OpenTime seconds before it is probed again
+	CircuitBreaker struct {
+		Failures int
+		OpenTime int
+	}
+	// Registry resolves the clients from Consul instead of the static
+	// settings in Clients
+	Registry consul.Config
 	// Clients is a map of services used by a DS.
 	Clients map[string]ClientInfo
 }
@@ -39,16 +122,44 @@ func (client ClientInfo) Endpoint() string {
 	return client.Host + ":" + strconv.Itoa(client.Port)
 }
 
-// Load config (based on EdgeX Go SDK code)
-func LoadConfig(confDir string) (config *Config, err error) {
-	fmt.Fprintf(os.Stdout, "LoadConfig confDir: %s\n", confDir)
-	confName := "configuration.toml"
+func (client ClientInfo) protocol() string {
+	if client.Protocol == "" {
+		return DefaultProtocol
+	}
+	return strings.ToLower(client.Protocol)
+}
 
+func (client ClientInfo) apiVersion() string {
+	if client.APIVersion == "" {
+		return APIv1
+	}
+	return strings.ToLower(client.APIVersion)
+}
+
+// apiURL returns the base URL of the REST API of the service.
+func (client ClientInfo) apiURL() string {
+	return client.protocol() + "://" + client.Endpoint() + "/api/" + client.apiVersion()
+}
+
+// ConfigPath returns the path of the configuration file confName in confDir,
+// ./res/configuration.toml by default.
+func ConfigPath(confDir string, confName string) string {
 	if len(confDir) == 0 {
 		confDir = "./res"
 	}
+	if len(confName) == 0 {
+		confName = "configuration.toml"
+	}
+	return confDir + "/" + confName
+}
 
-	path := confDir + "/" + confName
+// Load config (based on EdgeX Go SDK code) from the file confName in
+// confDir, ./res/configuration.toml by default. The keys of the file that
+// match no setting are returned.
+func LoadConfig(confDir string, confName string) (config *Config, unknown []string, err error) {
+	fmt.Fprintf(os.Stdout, "LoadConfig confDir: %s file: %s\n", confDir, confName)
+
+	path := ConfigPath(confDir, confName)
 
 	// As the toml package can panic if TOML is invalid,
 	// or elements are found that don't match members of
@@ -56,24 +167,25 @@ func LoadConfig(confDir string) (config *Config, err error) {
 	// from the panic and output a useful error.
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("could not load configuration file; invalid TOML (%s)", path)
+			config, unknown = nil, nil
+			err = fmt.Errorf("could not load configuration file; invalid TOML (%s): %v", path, r)
 		}
 	}()
 
 	config = &Config{}
 	contents, err := ioutil.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("could not load configuration file (%s): %v", path, err.Error())
+		return nil, nil, fmt.Errorf("could not load configuration file (%s): %v", path, err.Error())
 	}
 
-	// Decode the configuration from TOML
-	//
-	// TODO: invalid input can cause a SIGSEGV fatal error (INVESTIGATE)!!!
-	//       - test missing keys, keys with wrong type, ...
-	err = toml.Unmarshal(contents, config)
+	// Decode the configuration from TOML, keys of the wrong type are errors
+	metadata, err := toml.Decode(string(contents), config)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse configuration file (%s): %v", path, err.Error())
+		return nil, nil, fmt.Errorf("unable to parse configuration file (%s): %v", path, err.Error())
+	}
+	for _, key := range metadata.Undecoded() {
+		unknown = append(unknown, key.String())
 	}
 
-	return config, nil
+	return config, unknown, nil
 }
@@ -0,0 +1,98 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package edgex
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/edgexfoundry/go-ui-server/internal/consul"
+)
+
+// resolveTimeout limits how long start up waits for Consul
+const resolveTimeout = 10 * time.Second
+
+// stopRegistry stops following the services in Consul, nil if not started
+var stopRegistry context.CancelFunc
+
+// InitRegistry resolves the clients from Consul, if enabled, and keeps
+// following their healthy instances. Clients without one, or all of them
+// while Consul cannot be reached, fall back to their static settings.
+func InitRegistry(config consul.Config) error {
+	if stopRegistry != nil {
+		stopRegistry()
+		stopRegistry = nil
+	}
+	if !config.Enabled {
+		return nil
+	}
+	client, err := consul.New(config)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	for name, instance := range client.Resolve(ctx) {
+		useInstance(name, instance, nil)
+	}
+	cancel()
+	ctx, stopRegistry = context.WithCancel(context.Background())
+	client.Watch(ctx, useInstance)
+	return nil
+}
+
+// serviceOf returns the service of the client name.
+func serviceOf(name string) (string, bool) {
+	for service, n := range clientNames {
+		if n == name {
+			return service, true
+		}
+	}
+	return "", false
+}
+
+// useInstance points the client name at instance, or back at its static
+// settings if instance is nil.
+func useInstance(name string, instance *consul.Instance, err error) {
+	service, ok := serviceOf(name)
+	if !ok {
+		return
+	}
+	if instance == nil {
+		fmt.Fprintf(os.Stderr, "Client %s uses its static settings: %v\n", name, err)
+		if err := resetToStatic(service); err != nil {
+			fmt.Fprintf(os.Stderr, "Client %s: %v\n", name, err)
+		}
+		return
+	}
+	if info, ok := registry.Info(service); ok && info.Host == instance.Address && info.Port == instance.Port {
+		return
+	}
+	_, err = registry.Update(service, func(info *ClientInfo) {
+		info.Host = instance.Address
+		info.Port = instance.Port
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Client %s: %v\n", name, err)
+		return
+	}
+	fmt.Fprintf(os.Stdout, "Client %s resolved to %s\n", name, instance.Endpoint())
+}
+
+// resetToStatic sets service back to its configured settings and the
+// endpoint saved for it from the UI, if any.
+func resetToStatic(service string) error {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+	if err := registry.Reset(service); err != nil {
+		return err
+	}
+	if endpoint, ok := saved[clientNames[service]]; ok {
+		_, err := registry.Update(service, endpoint.apply)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package edgex
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-ui-server/internal/consul"
+	"github.com/edgexfoundry/go-ui-server/internal/consul/consultest"
+)
+
+// newTestConsul starts a Consul agent with the data service at address and
+// port, and returns it with the registry settings watching it.
+func newTestConsul(t *testing.T, address string, port int) (*consultest.Agent, consul.Config) {
+	agent := consultest.NewAgent(t, "edgex-core-data", 1, dataEntry(address, port))
+	host, agentPort := agent.HostPort()
+	return agent, consul.Config{
+		Enabled:       true,
+		Host:          host,
+		Port:          agentPort,
+		WaitTime:      1,
+		RetryInterval: 1,
+		Services:      map[string]string{clientNames["data"]: "edgex-core-data"},
+	}
+}
+
+func dataEntry(address string, port int) consultest.Entry {
+	return consultest.Entry{Node: "n", NodeAddress: "192.0.2.1", ID: "data", Address: address, Port: port}
+}
+
+// useTestRegistry replaces the registry by one with the static settings of
+// the data service for the test.
+func useTestRegistry(t *testing.T) {
+	old := registry
+	registry = NewRegistry()
+	t.Cleanup(func() {
+		if stopRegistry != nil {
+			stopRegistry()
+			stopRegistry = nil
+		}
+		registry = old
+	})
+	static := map[string]ClientInfo{"data": {Host: "static", Port: 48080, Protocol: "http"}}
+	if err := registry.Load(static, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitForEndpoint waits until the data service is called at endpoint.
+func waitForEndpoint(t *testing.T, endpoint string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		info, _ := registry.Info("data")
+		if info.Endpoint() == endpoint {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("data service at %s, want %s", info.Endpoint(), endpoint)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestRegistryFollowsConsul(t *testing.T) {
+	useTestRegistry(t)
+	agent, config := newTestConsul(t, "10.0.0.1", 48080)
+	if err := InitRegistry(config); err != nil {
+		t.Fatal(err)
+	}
+	// resolved before InitRegistry returns
+	if info, _ := registry.Info("data"); info.Host != "10.0.0.1" {
+		t.Fatalf("data service at %s, want 10.0.0.1", info.Endpoint())
+	}
+
+	agent.Set(2, http.StatusOK, dataEntry("10.0.0.2", 48081))
+	waitForEndpoint(t, "10.0.0.2:48081")
+
+	agent.Set(3, http.StatusInternalServerError)
+	waitForEndpoint(t, "static:48080")
+
+	agent.Set(4, http.StatusOK, dataEntry("10.0.0.3", 48080))
+	waitForEndpoint(t, "10.0.0.3:48080")
+}
+
+func TestRegistryConsulUnreachable(t *testing.T) {
+	useTestRegistry(t)
+	agent, config := newTestConsul(t, "10.0.0.1", 48080)
+	agent.Close()
+	if err := InitRegistry(config); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if info, _ := registry.Info("data"); info.Host != "static" {
+		t.Errorf("data service at %s without Consul, want its static settings", info.Endpoint())
+	}
+}
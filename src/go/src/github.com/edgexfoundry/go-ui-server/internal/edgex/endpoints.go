@@ -5,37 +5,387 @@
 package edgex
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
 	"github.com/edgexfoundry/go-ui-server/internal/fulcro"
 	"github.com/russolsen/transit"
 )
 
-var endpoints = make(map[string]interface{})
+// clientNames maps the services to their [Clients] section in the config
+var clientNames = map[string]string{
+	ClientData:          "Data",
+	ClientMetadata:      "Metadata",
+	ClientCommand:       "Command",
+	ClientLogging:       "Logging",
+	ClientExport:        "Export",
+	ClientNotifications: "Notifications",
+	ClientScheduler:     "Scheduler",
+}
 
 func getEndpoint(service string) string {
-	return HttpScheme + endpoints[service].(string) + APIv1Prefix + "/"
-}
-
-func InitEndpoints(config *Config) {
-	endpoints[ClientData] = config.Clients["Data"].Endpoint()
-	endpoints[ClientMetadata] = config.Clients["Metadata"].Endpoint()
-	endpoints[ClientCommand] = config.Clients["Command"].Endpoint()
-	endpoints[ClientLogging] = config.Clients["Logging"].Endpoint()
-	endpoints[ClientExport] = config.Clients["Export"].Endpoint()
-	endpoints[ClientNotifications] = config.Clients["Notifications"].Endpoint()
-	endpoints[ClientScheduler] = config.Clients["Scheduler"].Endpoint()
-}
-
-func SaveEndpoints(args map[interface{}]interface{}) (interface{}, error) {
-	endpoints[ClientData] = args[transit.Keyword(ClientData)]
-	endpoints[ClientMetadata] = args[transit.Keyword(ClientMetadata)]
-	endpoints[ClientCommand] = args[transit.Keyword(ClientCommand)]
-	endpoints[ClientLogging] = args[transit.Keyword(ClientLogging)]
-	endpoints[ClientExport] = args[transit.Keyword(ClientExport)]
-	endpoints[ClientNotifications] = args[transit.Keyword(ClientNotifications)]
-	endpoints[ClientScheduler] = args[transit.Keyword(ClientScheduler)]
+	return registry.URL(service)
+}
+
+// InitEndpoints sets up the registry with the clients of config and the
+// endpoints saved from the UI, replacing all settings it held before.
+func InitEndpoints(config *Config) error {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+	configured := make(map[string]ClientInfo, len(clientNames))
+	current := make(map[string]ClientInfo, len(clientNames))
+	for service, name := range clientNames {
+		info := config.Clients[name]
+		configured[service] = info
+		if endpoint, ok := saved[name]; ok {
+			endpoint.apply(&info)
+			fmt.Fprintf(os.Stdout, "Client %s uses the saved endpoint %s\n", name, endpoint.Endpoint())
+		}
+		current[service] = info
+	}
+	return registry.Load(configured, current)
+}
+
+// savedEndpoint is the endpoint of a service saved from the UI
+type savedEndpoint struct {
+	Host     string
+	Port     int
+	Protocol string `toml:",omitempty"`
+	Timeout  int    `toml:",omitempty"`
+}
+
+func (endpoint savedEndpoint) Endpoint() string {
+	return net.JoinHostPort(endpoint.Host, strconv.Itoa(endpoint.Port))
+}
+
+// apply sets the settings of info saved in endpoint.
+func (endpoint savedEndpoint) apply(info *ClientInfo) {
+	info.Host = endpoint.Host
+	info.Port = endpoint.Port
+	if endpoint.Protocol != "" {
+		info.Protocol = endpoint.Protocol
+	}
+	if endpoint.Timeout != 0 {
+		info.Timeout = endpoint.Timeout
+	}
+}
+
+// endpointState is the file keeping the saved endpoints across restarts,
+// laid out like the [Clients] of the configuration
+type endpointState struct {
+	Clients map[string]savedEndpoint
+}
+
+// stateFile is where saved endpoints are kept, they are not kept if empty
+var stateFile string
+
+// saved holds the endpoints saved so far by client name, guarded by
+// stateMutex
+var saved = make(map[string]savedEndpoint)
+var stateMutex sync.Mutex
+
+// pingTimeout limits how long a service may take to answer a ping
+const pingTimeout = 5 * time.Second
+
+// LoadEndpointState reads the endpoints saved in the state file of config,
+// to be set over those of the configuration by InitEndpoints.
+func LoadEndpointState(config *Config) error {
+	stateFile = config.Endpoints.StateFile
+	if stateFile == "" {
+		return nil
+	}
+	var state endpointState
+	if _, err := toml.DecodeFile(stateFile, &state); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("could not load endpoint state (%s): %v", stateFile, err)
+	}
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+	for name, endpoint := range state.Clients {
+		if _, ok := config.Clients[name]; ok {
+			saved[name] = endpoint
+		}
+	}
+	return nil
+}
+
+// writeEndpointState replaces the state file by one holding the saved
+// endpoints. The caller holds stateMutex.
+func writeEndpointState() error {
+	if stateFile == "" {
+		return nil
+	}
+	tmp := stateFile + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	// a file left over by an earlier failed write keeps its mode
+	if err = file.Chmod(0600); err != nil {
+		file.Close()
+		return err
+	}
+	err = toml.NewEncoder(file).Encode(endpointState{Clients: saved})
+	if e := file.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(tmp, stateFile)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// parseEndpoint reads the new endpoint of a service, either "host:port" or
+// a map of any of :host, :port, :protocol and :timeout, over the current
+// settings info.
+func parseEndpoint(arg interface{}, info ClientInfo) (savedEndpoint, error) {
+	endpoint := savedEndpoint{Host: info.Host, Port: info.Port, Protocol: info.Protocol, Timeout: info.Timeout}
+	switch v := arg.(type) {
+	case string:
+		host, p, err := net.SplitHostPort(v)
+		if err != nil {
+			return endpoint, err
+		}
+		port, err := strconv.Atoi(p)
+		if err != nil {
+			return endpoint, fmt.Errorf("invalid port %s", p)
+		}
+		endpoint.Host, endpoint.Port = host, port
+	case map[interface{}]interface{}:
+		if host, ok := v[transit.Keyword("host")]; ok {
+			endpoint.Host, _ = host.(string)
+		}
+		if port, ok := v[transit.Keyword("port")]; ok {
+			p, _ := port.(int64)
+			endpoint.Port = int(p)
+		}
+		if protocol, ok := v[transit.Keyword("protocol")]; ok {
+			s, _ := protocol.(string)
+			endpoint.Protocol = strings.ToLower(s)
+			if endpoint.Protocol != "http" && endpoint.Protocol != "https" {
+				return endpoint, fmt.Errorf("invalid protocol %q", s)
+			}
+		}
+		if timeout, ok := v[transit.Keyword("timeout")]; ok {
+			t, _ := timeout.(int64)
+			if t <= 0 {
+				return endpoint, fmt.Errorf("invalid timeout %v", timeout)
+			}
+			endpoint.Timeout = int(t)
+		}
+	default:
+		return endpoint, fmt.Errorf("not an endpoint")
+	}
+	if endpoint.Host == "" {
+		return endpoint, fmt.Errorf("missing host")
+	}
+	if endpoint.Port < 1 || endpoint.Port > 65535 {
+		return endpoint, fmt.Errorf("invalid port %d", endpoint.Port)
+	}
+	return endpoint, nil
+}
+
+// ping checks that the service set up as info answers. The request is not
+// made on behalf of a UI request, so its status is not recorded in one; the
+// pings of SaveEndpoints run concurrently.
+func ping(ctx context.Context, info ClientInfo) error {
+	client, err := newClient(info)
+	if err != nil {
+		return err
+	}
+	timeout, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	resp, err := client.R().SetContext(timeout).Get(info.apiURL() + "/ping")
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() != http.StatusOK || !pong(info, resp.String()) {
+		return fmt.Errorf("unexpected ping response: %s", resp.Status())
+	}
+	return nil
+}
+
+// pong tells whether reply answers a ping through the API of info: v1
+// answers "pong", v2 its version and the time.
+func pong(info ClientInfo, reply string) bool {
+	if info.apiVersion() == APIv1 {
+		return strings.TrimSpace(reply) == StatusResponse
+	}
+	var status struct {
+		APIVersion string `json:"apiVersion"`
+	}
+	return json.Unmarshal([]byte(reply), &status) == nil && status.APIVersion == info.apiVersion()
+}
+
+// SaveEndpoints changes the settings of the services given, after each of
+// them answered a ping at its new endpoint. Services not given, and settings
+// not given for a service, are left as they are. With force the endpoints
+// are changed even if a service is unreachable. The reachability of each
+// service is returned.
+func SaveEndpoints(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
+	changes := make(map[string]savedEndpoint)
+	var invalid []string
+	for service, name := range clientNames {
+		arg, ok := args[transit.Keyword(service)]
+		if !ok || arg == nil {
+			continue
+		}
+		info, _ := registry.Info(service)
+		endpoint, err := parseEndpoint(arg, info)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s endpoint %v: %v", name, arg, err))
+			continue
+		}
+		changes[service] = endpoint
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return nil, &fulcro.Error{Status: http.StatusBadRequest, Message: "Invalid " + strings.Join(invalid, ", ")}
+	}
+
+	pingContext := context.Background()
+	if ctx != nil {
+		pingContext = ctx.Request.Context()
+	}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	reachable := true
+	services := make([]map[string]interface{}, 0, len(changes))
+	for service, endpoint := range changes {
+		info, _ := registry.Info(service)
+		endpoint.apply(&info)
+		wg.Add(1)
+		go func(service string, info ClientInfo) {
+			defer wg.Done()
+			err := ping(pingContext, info)
+			check := map[string]interface{}{
+				"service":   transit.Keyword(service),
+				"endpoint":  info.Endpoint(),
+				"reachable": err == nil,
+				"error":     "",
+			}
+			if err != nil {
+				check["error"] = err.Error()
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			reachable = reachable && err == nil
+			services = append(services, check)
+		}(service, info)
+	}
+	wg.Wait()
+	sort.Slice(services, func(i, j int) bool {
+		return services[i]["service"].(transit.Keyword) < services[j]["service"].(transit.Keyword)
+	})
+
+	force, _ := args[transit.Keyword("force")].(bool)
+	save := reachable || force
+	if save {
+		stateMutex.Lock()
+		defer stateMutex.Unlock()
+		for service, endpoint := range changes {
+			if _, err := registry.Update(service, endpoint.apply); err != nil {
+				return nil, err
+			}
+			saved[clientNames[service]] = endpoint
+		}
+		if err := writeEndpointState(); err != nil {
+			return nil, fmt.Errorf("could not save endpoints: %v", err)
+		}
+	}
+	result := map[string]interface{}{
+		"saved":    save,
+		"services": services,
+	}
+	return fulcro.Keywordize(result, nil)
+}
+
+// ResetEndpoints sets the services given as :services, or all of them, back
+// to their configured settings and forgets their saved endpoints.
+func ResetEndpoints(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
+	var services []string
+	if list, ok := args[transit.Keyword("services")].([]interface{}); ok {
+		for _, s := range list {
+			service, _ := s.(transit.Keyword)
+			if _, ok := clientNames[string(service)]; !ok {
+				return nil, &fulcro.Error{Status: http.StatusBadRequest, Message: fmt.Sprintf("Unknown service %v", s)}
+			}
+			services = append(services, string(service))
+		}
+	} else {
+		services = registry.Services()
+	}
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+	for _, service := range services {
+		if err := registry.Reset(service); err != nil {
+			return nil, err
+		}
+		delete(saved, clientNames[service])
+	}
+	if err := writeEndpointState(); err != nil {
+		return nil, fmt.Errorf("could not save endpoints: %v", err)
+	}
 	return nil, nil
 }
 
-func Endpoints(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
-	return fulcro.Keywordize(endpoints, nil)
-}
\ No newline at end of file
+// Endpoints returns the "host:port" of each service, as edited in the UI,
+// and the current and configured settings of all services as :services.
+func Endpoints(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+	result := make(map[string]interface{})
+	var services []map[string]interface{}
+	for _, service := range registry.Services() {
+		info, _ := registry.Info(service)
+		configured, _ := registry.Configured(service)
+		result[service] = info.Endpoint()
+		services = append(services, map[string]interface{}{
+			"service":    transit.Keyword(service),
+			"host":       info.Host,
+			"port":       info.Port,
+			"protocol":   info.protocol(),
+			"timeout":    info.Timeout,
+			"url":        registry.URL(service),
+			"configured": configured.protocol() + "://" + configured.Endpoint(),
+			"changed":    info != configured,
+		})
+	}
+	result["services"] = services
+	return fulcro.Keywordize(result, nil)
+}
+
+// ServiceHealth returns the circuit breaker of each service: its :state,
+// the :failures in a row, the :last-error and when it last failed and, while
+// it is not closed, :until when the service is probed next.
+func ServiceHealth(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+	services := registry.Services()
+	result := make([]map[string]interface{}, len(services))
+	for i, service := range services {
+		status := registry.Breaker(service).Status()
+		result[i] = map[string]interface{}{
+			"type":         transit.Keyword("service-health"),
+			"id":           transit.Keyword(service),
+			"service":      service,
+			"state":        transit.Keyword(status.State),
+			"failures":     status.Failures,
+			"last-error":   status.LastError,
+			"last-failure": millis(status.LastFailure),
+			"until":        millis(status.Until),
+		}
+	}
+	return fulcro.Keywordize(result, nil)
+}
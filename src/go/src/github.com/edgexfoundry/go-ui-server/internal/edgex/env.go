@@ -0,0 +1,254 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package edgex
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/edgexfoundry/go-ui-server/internal/fulcro"
+)
+
+// EnvPrefix starts the names of the environment variables overriding the
+// configuration file.
+const EnvPrefix = "EDGEX_UI_"
+
+// maxEnvTables limits the tables the environment may add to an array of
+// tables, so that a mistyped index cannot allocate millions of them
+const maxEnvTables = 100
+
+// ApplyEnv overrides settings of config with the environment variables in
+// environ, given as "NAME=value". A setting is named after its TOML path in
+// upper case joined by "_", such as EDGEX_UI_SERVER_PORT or
+// EDGEX_UI_CLIENTS_DATA_HOST. Lists are separated by commas and the tables
+// of an array of tables are numbered from 0, as in
+// EDGEX_UI_COMMANDPOLICY_RULES_0_DECISION. The names of variables with the
+// prefix that match no setting are returned.
+func ApplyEnv(config *Config, environ []string) ([]string, error) {
+	env := make(map[string]string)
+	for _, kv := range environ {
+		i := strings.Index(kv, "=")
+		if i < 0 || !strings.HasPrefix(kv[:i], EnvPrefix) {
+			continue
+		}
+		env[kv[:i]] = kv[i+1:]
+	}
+	used := make(map[string]bool)
+	err := applyEnv(reflect.ValueOf(config).Elem(), strings.TrimSuffix(EnvPrefix, "_"), env, used)
+	if err != nil {
+		return nil, err
+	}
+	var unknown []string
+	for name := range env {
+		if !used[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+func applyEnv(v reflect.Value, name string, env map[string]string, used map[string]bool) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			fieldName := name + "_" + strings.ToUpper(field.Name)
+			// embedded structs are flattened, as in TOML
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				fieldName = name
+			}
+			if err := applyEnv(v.Field(i), fieldName, env, used); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		return applyEnvMap(v, name, env, used)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			return applyEnvTables(v, name, env, used)
+		}
+	}
+	value, ok := env[name]
+	if !ok {
+		return nil
+	}
+	used[name] = true
+	if err := setValue(v, value); err != nil {
+		return fmt.Errorf("invalid value of %s: %v", name, err)
+	}
+	return nil
+}
+
+// applyEnvMap overrides the entries of a map with string keys. Keys match
+// existing entries regardless of case; new tables, such as clients, are
+// named in title case and new values as in the variable name.
+func applyEnvMap(v reflect.Value, name string, env map[string]string, used map[string]bool) error {
+	prefix := name + "_"
+	elem := v.Type().Elem()
+	keys := make(map[string]string)
+	for _, key := range v.MapKeys() {
+		keys[strings.ToUpper(key.String())] = key.String()
+	}
+	for envName := range env {
+		if !strings.HasPrefix(envName, prefix) {
+			continue
+		}
+		rest := envName[len(prefix):]
+		if elem.Kind() != reflect.Struct {
+			key, ok := keys[strings.ToUpper(rest)]
+			if !ok {
+				key = rest
+			}
+			entry := reflect.New(elem).Elem()
+			if err := setValue(entry, env[envName]); err != nil {
+				return fmt.Errorf("invalid value of %s: %v", envName, err)
+			}
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			v.SetMapIndex(reflect.ValueOf(key), entry)
+			used[envName] = true
+			continue
+		}
+		for i := 0; i < elem.NumField(); i++ {
+			suffix := "_" + strings.ToUpper(elem.Field(i).Name)
+			if strings.HasSuffix(rest, suffix) && len(rest) > len(suffix) {
+				key := strings.TrimSuffix(rest, suffix)
+				if _, ok := keys[key]; !ok {
+					keys[key] = strings.Title(strings.ToLower(key))
+				}
+			}
+		}
+	}
+	if elem.Kind() != reflect.Struct {
+		return nil
+	}
+	if len(keys) > 0 && v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	for upper, key := range keys {
+		entry := reflect.New(elem).Elem()
+		current := v.MapIndex(reflect.ValueOf(key))
+		if current.IsValid() {
+			entry.Set(current)
+		}
+		before := len(used)
+		if err := applyEnv(entry, prefix+upper, env, used); err != nil {
+			return err
+		}
+		if len(used) > before {
+			v.SetMapIndex(reflect.ValueOf(key), entry)
+		}
+	}
+	return nil
+}
+
+// applyEnvTables overrides the tables of an array of tables, adding tables
+// for indexes past its end.
+func applyEnvTables(v reflect.Value, name string, env map[string]string, used map[string]bool) error {
+	prefix := name + "_"
+	size := v.Len()
+	for envName := range env {
+		if !strings.HasPrefix(envName, prefix) {
+			continue
+		}
+		index := strings.SplitN(envName[len(prefix):], "_", 2)[0]
+		if i, err := strconv.Atoi(index); err == nil && i >= size && i < maxEnvTables {
+			size = i + 1
+		}
+	}
+	if size > v.Len() {
+		tables := reflect.MakeSlice(v.Type(), size, size)
+		reflect.Copy(tables, v)
+		v.Set(tables)
+	}
+	for i := 0; i < v.Len(); i++ {
+		if err := applyEnv(v.Index(i), prefix+strconv.Itoa(i), env, used); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		if strings.TrimSpace(value) != "" {
+			items = strings.Split(value, ",")
+		}
+		list := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(list.Index(i), strings.TrimSpace(item)); err != nil {
+				return err
+			}
+		}
+		v.Set(list)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// DumpConfig writes config as TOML, with tokens and secrets masked.
+func DumpConfig(w io.Writer, config *Config) error {
+	return toml.NewEncoder(w).Encode(maskConfig(config))
+}
+
+// maskConfig returns a copy of config with tokens and secrets masked.
+func maskConfig(config *Config) Config {
+	c := *config
+	c.Clients = make(map[string]ClientInfo, len(config.Clients))
+	for name, info := range config.Clients {
+		if info.Token != "" {
+			info.Token = fulcro.Masked
+		}
+		c.Clients[name] = info
+	}
+	if c.OIDC.ClientSecret != "" {
+		c.OIDC.ClientSecret = fulcro.Masked
+	}
+	if c.Registry.Token != "" {
+		c.Registry.Token = fulcro.Masked
+	}
+	return c
+}
@@ -0,0 +1,128 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package edgex
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/edgexfoundry/go-ui-server/internal/auth"
+	"github.com/edgexfoundry/go-ui-server/internal/fulcro"
+	"github.com/edgexfoundry/go-ui-server/internal/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/russolsen/transit"
+)
+
+const (
+	// oidcProviderName marks sessions logged in through OpenID Connect
+	oidcProviderName = "oidc"
+	// oidcStateCookie binds a login started at the provider to the browser
+	oidcStateCookie = "EDGEX_UI_OIDC_STATE"
+	// clientSessionCookie tells the web client that it is logged in
+	clientSessionCookie = "EDGEX_SESSION_ID"
+)
+
+// oidcProvider is set if users may log in through OpenID Connect
+var oidcProvider *oidc.Provider
+
+func InitOIDC(config oidc.Config) error {
+	if !config.Enabled {
+		return nil
+	}
+	provider, err := oidc.New(config)
+	if err != nil {
+		return err
+	}
+	oidcProvider = provider
+	return nil
+}
+
+// localLoginDisabled returns an error if only OpenID Connect logins are
+// allowed.
+func localLoginDisabled() error {
+	if oidcProvider != nil && oidcProvider.Config().DisableLocalLogin {
+		return &fulcro.Error{Status: http.StatusForbidden, Message: "Local login is disabled"}
+	}
+	return nil
+}
+
+// LoginMethods tells the login page which ways to log in are offered.
+func LoginMethods(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+	result := map[transit.Keyword]interface{}{
+		transit.Keyword("local"): localLoginDisabled() == nil,
+		transit.Keyword("oidc"):  oidcProvider != nil,
+	}
+	return result, nil
+}
+
+func setStateCookie(c *gin.Context, path string, state string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     path,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   cookies.IsSecure(c.Request),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// AddOIDC adds the routes sending the browser to the identity provider and
+// taking it back, logged in, from there, below the base path of r.
+func AddOIDC(r *gin.RouterGroup) {
+	if oidcProvider == nil {
+		return
+	}
+	base := r.BasePath()
+	statePath := path.Join(base, "auth/oidc")
+
+	r.GET("/auth/oidc/login", func(c *gin.Context) {
+		url, state, err := oidcProvider.AuthURL(c.Request.Context())
+		if err != nil {
+			c.String(http.StatusBadGateway, err.Error())
+			return
+		}
+		setStateCookie(c, statePath, state, 600)
+		c.Redirect(http.StatusFound, url)
+	})
+
+	r.GET("/auth/oidc/callback", func(c *gin.Context) {
+		client := fulcro.NewContext(c).RemoteIP()
+		state := c.Query("state")
+		cookie, err := c.Request.Cookie(oidcStateCookie)
+		setStateCookie(c, statePath, "", -1)
+		if err != nil || state == "" || cookie.Value != state {
+			auth.Security.Log(auth.EventLoginFailed, "", client, "OIDC state mismatch")
+			c.String(http.StatusBadRequest, oidc.ErrUnknownState.Error())
+			return
+		}
+		if reason := c.Query("error"); reason != "" {
+			auth.Security.Log(auth.EventLoginFailed, "", client, "OIDC: "+reason)
+			c.String(http.StatusUnauthorized, "Login failed: "+reason)
+			return
+		}
+		identity, err := oidcProvider.Exchange(c.Request.Context(), state, c.Query("code"))
+		if err != nil {
+			auth.Security.Log(auth.EventLoginFailed, "", client, "OIDC: "+err.Error())
+			c.String(http.StatusUnauthorized, "Login failed: "+err.Error())
+			return
+		}
+		session, err := auth.Sessions.CreateExternal(identity.Name, identity.Role, oidcProviderName, c.Request)
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		auth.Security.Log(auth.EventLoginSucceeded, identity.Name, client, "OIDC role "+identity.Role.String())
+		auth.SetSessionCookie(c.Writer, c.Request, session, cookies)
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:   clientSessionCookie,
+			Value:  session.Handle,
+			Path:   base,
+			MaxAge: 3600,
+			Secure: cookies.IsSecure(c.Request),
+		})
+		c.Redirect(http.StatusFound, base)
+	})
+}
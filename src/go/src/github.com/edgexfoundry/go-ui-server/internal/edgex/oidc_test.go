@@ -0,0 +1,143 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package edgex
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/edgexfoundry/go-ui-server/internal/auth"
+	"github.com/edgexfoundry/go-ui-server/internal/oidc"
+	"github.com/edgexfoundry/go-ui-server/internal/oidc/oidctest"
+	"github.com/gin-gonic/gin"
+)
+
+// testOIDCConfig returns the configuration of a client of tp with the
+// callback below base.
+func testOIDCConfig(tp *oidctest.Provider, base string) oidc.Config {
+	return oidc.Config{
+		Enabled:      true,
+		Issuer:       tp.URL,
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  "https://ui.example.com" + base + "auth/oidc/callback",
+		Groups:       map[string]string{"ops": "operator"},
+	}
+}
+
+// setupOIDC serves the OIDC routes below base with a stand-in provider.
+func setupOIDC(t *testing.T, base string) (*gin.Engine, *oidctest.Provider) {
+	gin.SetMode(gin.TestMode)
+	tp := oidctest.NewProvider(t)
+	if err := InitOIDC(testOIDCConfig(tp, base)); err != nil {
+		t.Fatal(err)
+	}
+	cookies = auth.CookieOptions{Path: base, Secure: true}
+	t.Cleanup(func() { oidcProvider, cookies = nil, auth.CookieOptions{Path: "/"} })
+	r := gin.New()
+	AddOIDC(r.Group(base))
+	return r, tp
+}
+
+func serve(r *gin.Engine, target string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func cookie(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// startLogin starts a login below base and follows the browser to the
+// provider tp, returning the state cookie and the callback URL it is sent
+// back to.
+func startLogin(t *testing.T, r *gin.Engine, tp *oidctest.Provider, base string) (*http.Cookie, *url.URL) {
+	w := serve(r, base+"auth/oidc/login")
+	if w.Code != http.StatusFound {
+		t.Fatalf("login: %d %s", w.Code, w.Body.String())
+	}
+	state := cookie(w, oidcStateCookie)
+	if state == nil || state.Path != base+"auth/oidc" || !state.HttpOnly || !state.Secure {
+		t.Fatalf("state cookie %v, want a Secure HttpOnly cookie for %sauth/oidc", state, base)
+	}
+	callback, err := tp.Authorize(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state, callback
+}
+
+func TestOIDCLogin(t *testing.T) {
+	const base = "/edgex-ui/"
+	r, tp := setupOIDC(t, base)
+	state, callback := startLogin(t, r, tp, base)
+
+	w := serve(r, callback.RequestURI(), state)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != base {
+		t.Fatalf("callback: %d to %q, want %d to %q: %s", w.Code, w.Header().Get("Location"), http.StatusFound, base, w.Body.String())
+	}
+	if c := cookie(w, oidcStateCookie); c == nil || c.MaxAge >= 0 {
+		t.Errorf("state cookie %v not cleared", c)
+	}
+	token := cookie(w, auth.SessionCookie)
+	if token == nil || token.Path != base || !token.Secure || !token.HttpOnly {
+		t.Fatalf("session cookie %v, want a Secure HttpOnly cookie for %s", token, base)
+	}
+	session, ok := auth.Sessions.Get(token.Value)
+	if !ok {
+		t.Fatal("no session for the session cookie")
+	}
+	if session.User != "alice" || session.Provider != oidcProviderName || session.Role != auth.RoleOperator {
+		t.Errorf("session of %s through %q as %s, want alice through %q as operator",
+			session.User, session.Provider, session.Role, oidcProviderName)
+	}
+	handle := cookie(w, clientSessionCookie)
+	if handle == nil || handle.Value != session.Handle || handle.Path != base {
+		t.Errorf("client session cookie %v, want %s for %s", handle, session.Handle, base)
+	}
+}
+
+func TestOIDCCallbackState(t *testing.T) {
+	const base = "/"
+	r, tp := setupOIDC(t, base)
+	state, callback := startLogin(t, r, tp, base)
+
+	forged := &http.Cookie{Name: oidcStateCookie, Value: "forged"}
+	tests := []struct {
+		name    string
+		target  string
+		cookies []*http.Cookie
+		status  int
+	}{
+		{"no state cookie", callback.RequestURI(), nil, http.StatusBadRequest},
+		{"other state cookie", callback.RequestURI(), []*http.Cookie{forged}, http.StatusBadRequest},
+		{"no state", "/auth/oidc/callback?code=x", []*http.Cookie{state}, http.StatusBadRequest},
+		{"provider error", "/auth/oidc/callback?error=access_denied&state=" + url.QueryEscape(state.Value),
+			[]*http.Cookie{state}, http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(r, test.target, test.cookies...)
+			if w.Code != test.status {
+				t.Errorf("callback: %d, want %d: %s", w.Code, test.status, w.Body.String())
+			}
+			if c := cookie(w, auth.SessionCookie); c != nil {
+				t.Errorf("session cookie set: %v", c)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package edgex
+
+import (
+	"net/http"
+
+	"github.com/edgexfoundry/go-ui-server/internal/edgex/client"
+	"github.com/edgexfoundry/go-ui-server/internal/fulcro"
+	"github.com/edgexfoundry/go-ui-server/internal/policy"
+)
+
+func InitCommandPolicy(config policy.Config) error {
+	return policy.Commands.Configure(config)
+}
+
+// commandTargets returns a function giving the policy target of each command
+// of a device. The device is only looked up in the metadata service if a
+// policy is configured.
+func commandTargets(ctx *fulcro.Context, deviceId string) (func(command string) policy.Target, error) {
+	var device client.Device
+	if policy.Commands.Enabled() {
+		var err error
+		device, err = edgexClient(ctx).Device(deviceId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return func(command string) policy.Target {
+		return policy.Target{Device: device.Name, Labels: device.Labels, Profile: device.Profile.Name, Command: command}
+	}, nil
+}
+
+// checkCommandPolicy returns an error unless the policy allows command to be
+// issued, confirmed tells whether the user confirmed it a second time. The
+// command is counted against the rate limits, the ticket returned gives it
+// back with policy.Commands.Release if it is never sent.
+func checkCommandPolicy(ctx *fulcro.Context, deviceId string, command string, confirmed bool) (policy.Ticket, error) {
+	targets, err := commandTargets(ctx, deviceId)
+	if err != nil {
+		return policy.Ticket{}, err
+	}
+	target := targets(command)
+	switch policy.Commands.Decide(target) {
+	case policy.Deny:
+		return policy.Ticket{}, &fulcro.Error{Status: http.StatusForbidden, Message: "Command " + command + " is not allowed"}
+	case policy.Confirm:
+		if !confirmed {
+			return policy.Ticket{}, &fulcro.Error{Status: http.StatusPreconditionRequired, Message: "Command " + command + " must be confirmed"}
+		}
+	}
+	ticket, err := policy.Commands.Take(target)
+	if err != nil {
+		return ticket, &fulcro.Error{Status: http.StatusTooManyRequests, Message: err.Error()}
+	}
+	return ticket, nil
+}
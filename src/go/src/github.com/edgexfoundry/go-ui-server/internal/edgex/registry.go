@@ -0,0 +1,194 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package edgex
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/edgexfoundry/go-ui-server/internal/breaker"
+	"github.com/edgexfoundry/go-ui-server/internal/edgex/client"
+	"gopkg.in/resty.v1"
+)
+
+// Registry holds the client settings of each EdgeX service, as configured
+// and as changed since, together with the REST client built from them and
+// the circuit breaker of the service. It is safe for concurrent use.
+type Registry struct {
+	mutex      sync.RWMutex
+	configured map[string]ClientInfo
+	infos      map[string]ClientInfo
+	clients    map[string]*resty.Client
+	breakers   *breaker.Set
+}
+
+// registry holds the services used by the queries and mutations
+var registry = NewRegistry()
+
+// NewRegistry returns an empty registry whose open breakers ping their
+// services to close again.
+func NewRegistry() *Registry {
+	r := &Registry{
+		configured: make(map[string]ClientInfo),
+		infos:      make(map[string]ClientInfo),
+		clients:    make(map[string]*resty.Client),
+		breakers:   breaker.NewSet(breaker.DefaultConfig),
+	}
+	r.breakers.SetProber(r.ping)
+	return r
+}
+
+// ping checks whether service answers at its current settings.
+func (r *Registry) ping(service string) error {
+	info, ok := r.Info(service)
+	if !ok {
+		return fmt.Errorf("unknown service %s", service)
+	}
+	return ping(context.Background(), info)
+}
+
+// Load replaces the configured settings of the services by configured, and
+// their current settings by those in current, or the configured ones if a
+// service is missing there. Nothing is changed if a client cannot be set up.
+func (r *Registry) Load(configured map[string]ClientInfo, current map[string]ClientInfo) error {
+	infos := make(map[string]ClientInfo, len(configured))
+	clients := make(map[string]*resty.Client, len(configured))
+	for service, info := range configured {
+		client, err := newClient(info)
+		if err != nil {
+			return fmt.Errorf("invalid configuration of client %s: %v", service, err)
+		}
+		if changed, ok := current[service]; ok && changed != info {
+			info = changed
+			client, err = newClient(info)
+			if err != nil {
+				return fmt.Errorf("invalid settings of client %s: %v", service, err)
+			}
+		}
+		infos[service] = info
+		clients[service] = client
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.configured = make(map[string]ClientInfo, len(configured))
+	for service, info := range configured {
+		r.configured[service] = info
+	}
+	for service, info := range infos {
+		if r.infos[service] != info {
+			r.breakers.Reset(service)
+		}
+	}
+	r.infos = infos
+	r.clients = clients
+	return nil
+}
+
+// Info returns the current settings of service.
+func (r *Registry) Info(service string) (ClientInfo, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	info, ok := r.infos[service]
+	return info, ok
+}
+
+// Configured returns the settings of service in the configuration.
+func (r *Registry) Configured(service string) (ClientInfo, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	info, ok := r.configured[service]
+	return info, ok
+}
+
+// Client returns the REST client of service, nil if it is unknown.
+func (r *Registry) Client(service string) *resty.Client {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.clients[service]
+}
+
+// URL returns the base URL of the API of service, empty if it is unknown.
+func (r *Registry) URL(service string) string {
+	info, ok := r.Info(service)
+	if !ok {
+		return ""
+	}
+	return info.apiURL() + "/"
+}
+
+// APIVersion returns the version of the API of service, v1 if it is unknown.
+func (r *Registry) APIVersion(service string) string {
+	info, _ := r.Info(service)
+	return info.apiVersion()
+}
+
+// Retry returns how reads from service are retried, never if it is unknown.
+func (r *Registry) Retry(service string) client.Retry {
+	info, ok := r.Info(service)
+	if !ok {
+		return client.Retry{}
+	}
+	return info.retry()
+}
+
+// Services returns the names of all services, sorted.
+func (r *Registry) Services() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	services := make([]string, 0, len(r.infos))
+	for service := range r.infos {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// Breaker returns the circuit breaker of service. It is closed again
+// whenever the settings of service change.
+func (r *Registry) Breaker(service string) *breaker.Breaker {
+	return r.breakers.Get(service)
+}
+
+// Set replaces the settings of service by info.
+func (r *Registry) Set(service string, info ClientInfo) error {
+	client, err := newClient(info)
+	if err != nil {
+		return err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.infos[service] = info
+	r.clients[service] = client
+	r.breakers.Reset(service)
+	return nil
+}
+
+// Update changes the current settings of service with change, leaving the
+// settings it does not touch as they are, and returns the new settings.
+func (r *Registry) Update(service string, change func(info *ClientInfo)) (ClientInfo, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	info := r.infos[service]
+	change(&info)
+	client, err := newClient(info)
+	if err != nil {
+		return info, err
+	}
+	r.infos[service] = info
+	r.clients[service] = client
+	r.breakers.Reset(service)
+	return info, nil
+}
+
+// Reset sets service back to its configured settings.
+func (r *Registry) Reset(service string) error {
+	info, ok := r.Configured(service)
+	if !ok {
+		return nil
+	}
+	return r.Set(service, info)
+}
@@ -0,0 +1,397 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package edgex
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/edgexfoundry/go-ui-server/internal/auth"
+	"github.com/edgexfoundry/go-ui-server/internal/breaker"
+	"github.com/edgexfoundry/go-ui-server/internal/consul"
+	"github.com/edgexfoundry/go-ui-server/internal/policy"
+)
+
+// watchInterval is how often the configuration file is checked for changes
+const watchInterval = 2 * time.Second
+
+// restartSettings cannot change while the server is running, a change of
+// them or of any setting below them only takes effect after a restart
+var restartSettings = []string{
+	"Server",
+	"Audit",
+	"Endpoints",
+	"OIDC",
+	"Login.SecurityLog",
+}
+
+// liveSection is a part of the configuration applied while running. All
+// sections are validated before any of them is applied, so that a reload
+// changes either all of them or none.
+type liveSection struct {
+	// settings are the top level settings making up the section
+	settings []string
+	validate func(config *Config) error
+	apply    func(config *Config) error
+}
+
+var liveSections = []liveSection{
+	{[]string{"Clients", "Registry"}, validateClients, applyClients},
+	{[]string{"CommandPolicy"}, validateCommandPolicy, applyCommandPolicy},
+	{[]string{"CircuitBreaker"}, nil, applyCircuitBreaker},
+	{[]string{"Password"}, validatePassword, applyPassword},
+	{[]string{"Session"}, nil, applySession},
+	{[]string{"Login"}, nil, applyLogin},
+}
+
+func validateClients(config *Config) error {
+	for _, name := range clientNames {
+		if _, err := newClient(config.Clients[name]); err != nil {
+			return fmt.Errorf("invalid configuration of client %s: %v", name, err)
+		}
+	}
+	if config.Registry.Enabled {
+		if _, err := consul.New(config.Registry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyClients(config *Config) error {
+	// the registry must not follow Consul into the old settings
+	if stopRegistry != nil {
+		stopRegistry()
+		stopRegistry = nil
+	}
+	if err := InitEndpoints(config); err != nil {
+		return err
+	}
+	return InitRegistry(config.Registry)
+}
+
+func validateCommandPolicy(config *Config) error {
+	_, err := policy.New(config.CommandPolicy)
+	return err
+}
+
+func applyCommandPolicy(config *Config) error {
+	return InitCommandPolicy(config.CommandPolicy)
+}
+
+func applyCircuitBreaker(config *Config) error {
+	registry.breakers.Configure(breaker.Config{
+		Failures: config.CircuitBreaker.Failures,
+		OpenTime: time.Duration(config.CircuitBreaker.OpenTime) * time.Second,
+	})
+	return nil
+}
+
+func validatePassword(config *Config) error {
+	if err := config.Password.HashConfig.Validate(); err != nil {
+		return fmt.Errorf("invalid password configuration: %v", err)
+	}
+	return nil
+}
+
+func applyPassword(config *Config) error {
+	return auth.Users.Configure(config.Password.PasswordPolicy, config.Password.HashConfig)
+}
+
+func applySession(config *Config) error {
+	auth.Sessions.Configure(
+		time.Duration(config.Session.IdleTimeout)*time.Minute,
+		time.Duration(config.Session.AbsoluteTimeout)*time.Minute)
+	return nil
+}
+
+func applyLogin(config *Config) error {
+	auth.Logins.Configure(auth.LockoutConfig{
+		MaxFailures:       config.Login.MaxFailures,
+		LockoutDuration:   time.Duration(config.Login.LockoutDuration) * time.Minute,
+		BackoffBase:       time.Duration(config.Login.BackoffBase) * time.Second,
+		BackoffMax:        time.Duration(config.Login.BackoffMax) * time.Second,
+		GlobalMaxFailures: config.Login.GlobalMaxFailures,
+		GlobalWindow:      time.Duration(config.Login.GlobalWindow) * time.Minute,
+	})
+	return nil
+}
+
+// ApplyConfig validates the settings of config that may change while running
+// and, if they are all valid, applies them: the clients and registry, the
+// command policy, the circuit breakers, the password policy, the session
+// timeouts and the login throttling.
+func ApplyConfig(config *Config) error {
+	return applySections(config, func(liveSection) bool { return true })
+}
+
+// applySections validates all live sections of config and applies those
+// selected.
+func applySections(config *Config, selected func(liveSection) bool) error {
+	for _, section := range liveSections {
+		if section.validate == nil {
+			continue
+		}
+		if err := section.validate(config); err != nil {
+			return err
+		}
+	}
+	for _, section := range liveSections {
+		if !selected(section) {
+			continue
+		}
+		if err := section.apply(config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadEffectiveConfig loads the configuration file confName in confDir and
+// overrides its settings with the environment, returning the names of the
+// environment variables matching no setting. A ConfigError lists all
+// problems if the file has unknown keys or the result is not valid.
+func LoadEffectiveConfig(confDir string, confName string) (*Config, []string, error) {
+	config, unknownKeys, err := LoadConfig(confDir, confName)
+	if err != nil {
+		return nil, nil, err
+	}
+	unknown, err := ApplyEnv(config, os.Environ())
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkConfig(ConfigPath(confDir, confName), config, unknownKeys); err != nil {
+		return nil, unknown, err
+	}
+	return config, unknown, nil
+}
+
+// Reloader applies the configuration file again when it changes or the
+// process receives SIGHUP.
+type Reloader struct {
+	mutex    sync.Mutex
+	confDir  string
+	confName string
+	// config is the configuration in effect
+	config *Config
+	// modTime and size tell whether the file changed since last loaded
+	modTime time.Time
+	size    int64
+}
+
+// NewReloader returns a reloader of the configuration file confName in
+// confDir, with config being in effect.
+func NewReloader(confDir string, confName string, config *Config) *Reloader {
+	r := &Reloader{confDir: confDir, confName: confName, config: config}
+	r.modTime, r.size = r.stat()
+	return r
+}
+
+func (r *Reloader) stat() (time.Time, int64) {
+	info, err := os.Stat(ConfigPath(r.confDir, r.confName))
+	if err != nil {
+		return time.Time{}, 0
+	}
+	return info.ModTime(), info.Size()
+}
+
+// Watch reloads the configuration whenever the file changes or SIGHUP is
+// received. It does not return.
+func (r *Reloader) Watch() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-hup:
+			fmt.Fprintln(os.Stdout, "SIGHUP received, reloading configuration")
+		case <-ticker.C:
+			modTime, size := r.stat()
+			r.mutex.Lock()
+			changed := !modTime.IsZero() && (!modTime.Equal(r.modTime) || size != r.size)
+			r.mutex.Unlock()
+			if !changed {
+				continue
+			}
+			fmt.Fprintln(os.Stdout, "Configuration file changed, reloading configuration")
+		}
+		if err := r.Reload(); err != nil {
+			fmt.Fprintf(os.Stderr, "configuration not reloaded: %v\n", err)
+		}
+	}
+}
+
+// Reload loads the configuration again and applies the live sections that
+// changed. The configuration in effect is kept if the new one is invalid.
+// Changes of settings that need a restart are logged but not applied.
+func (r *Reloader) Reload() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.modTime, r.size = r.stat()
+	config, unknown, err := LoadEffectiveConfig(r.confDir, r.confName)
+	if err != nil {
+		return err
+	}
+	for _, name := range unknown {
+		fmt.Fprintf(os.Stderr, "ignoring unknown setting %s\n", name)
+	}
+	changes := diffConfig(r.config, config)
+	if len(changes) == 0 {
+		fmt.Fprintln(os.Stdout, "Configuration unchanged")
+		return nil
+	}
+	changed := make(map[string]bool)
+	var restart []string
+	for _, change := range changes {
+		if restartSetting(change.setting) != "" {
+			restart = append(restart, change.setting)
+			continue
+		}
+		changed[topSetting(change.setting)] = true
+	}
+	err = applySections(config, func(section liveSection) bool {
+		for _, setting := range section.settings {
+			if changed[setting] {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		note := ""
+		if restartSetting(change.setting) != "" {
+			note = " (needs a restart)"
+		}
+		fmt.Fprintf(os.Stdout, "  %s: %s -> %s%s\n", change.setting, change.old, change.new, note)
+	}
+	// the settings needing a restart stay in effect as they were
+	for _, setting := range restart {
+		copySetting(config, r.config, restartSetting(setting))
+	}
+	r.config = config
+	fmt.Fprintf(os.Stdout, "Configuration reloaded, %d changes\n", len(changes))
+	if len(restart) > 0 {
+		fmt.Fprintf(os.Stdout, "Restart to apply %s\n", strings.Join(restart, ", "))
+	}
+	return nil
+}
+
+// settingChange is a setting whose value changed, masked if secret
+type settingChange struct {
+	setting string
+	old     string
+	new     string
+}
+
+// diffConfig returns the settings that differ between old and new, named by
+// their TOML path, in order.
+func diffConfig(old *Config, new *Config) []settingChange {
+	before := make(map[string]string)
+	after := make(map[string]string)
+	flatten(reflect.ValueOf(maskConfig(old)), "", before)
+	flatten(reflect.ValueOf(maskConfig(new)), "", after)
+	var changes []settingChange
+	for setting, value := range after {
+		if previous, ok := before[setting]; !ok || previous != value {
+			if !ok {
+				previous = "(none)"
+			}
+			changes = append(changes, settingChange{setting, previous, value})
+		}
+	}
+	for setting, value := range before {
+		if _, ok := after[setting]; !ok {
+			changes = append(changes, settingChange{setting, value, "(none)"})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].setting < changes[j].setting })
+	return changes
+}
+
+// flatten adds the value of each setting in v to settings, named after its
+// path below name.
+func flatten(v reflect.Value, name string, settings map[string]string) {
+	join := func(field string) string {
+		if name == "" {
+			return field
+		}
+		return name + "." + field
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			// embedded structs are flattened, as in TOML
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				flatten(v.Field(i), name, settings)
+				continue
+			}
+			flatten(v.Field(i), join(field.Name), settings)
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			flatten(v.MapIndex(key), join(key.String()), settings)
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			settings[name] = fmt.Sprint(v.Interface())
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			flatten(v.Index(i), name+"["+strconv.Itoa(i)+"]", settings)
+		}
+	case reflect.String:
+		settings[name] = strconv.Quote(v.String())
+	default:
+		settings[name] = fmt.Sprint(v.Interface())
+	}
+}
+
+// restartSetting returns the one of restartSettings that setting is, or is
+// below, empty if there is none.
+func restartSetting(setting string) string {
+	for _, s := range restartSettings {
+		if setting == s || strings.HasPrefix(setting, s+".") || strings.HasPrefix(setting, s+"[") {
+			return s
+		}
+	}
+	return ""
+}
+
+// topSetting returns the top level setting setting is part of.
+func topSetting(setting string) string {
+	if i := strings.IndexAny(setting, ".["); i >= 0 {
+		return setting[:i]
+	}
+	return setting
+}
+
+// copySetting sets the struct field named by the path setting in dst to its
+// value in src.
+func copySetting(dst *Config, src *Config, setting string) {
+	d := reflect.ValueOf(dst).Elem()
+	s := reflect.ValueOf(src).Elem()
+	for _, field := range strings.Split(setting, ".") {
+		d = d.FieldByName(field)
+		s = s.FieldByName(field)
+	}
+	d.Set(s)
+}
@@ -0,0 +1,296 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package edgex
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"strings"
+
+	"github.com/edgexfoundry/go-ui-server/internal/edgex/client"
+	"github.com/edgexfoundry/go-ui-server/internal/fulcro"
+	"github.com/russolsen/transit"
+)
+
+// The results below are the EdgeX objects as the web client normalizes
+// them: maps with the entity type as :type, the id and enumerations as
+// keywords and secrets masked. Nested objects the client takes as they are
+// keep the JSON of the EdgeX services.
+
+// plain returns v as the maps and slices it encodes to in JSON.
+func plain(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var result interface{}
+	if err = json.Unmarshal(data, &result); err != nil {
+		return nil
+	}
+	return result
+}
+
+// masked returns Masked in place of a non-empty secret.
+func masked(secret string) string {
+	if secret == "" {
+		return secret
+	}
+	return fulcro.Masked
+}
+
+// maskAddressable hides the credentials of addressable.
+func maskAddressable(addressable client.Addressable) client.Addressable {
+	addressable.Password = masked(addressable.Password)
+	addressable.Cert = masked(addressable.Cert)
+	addressable.Key = masked(addressable.Key)
+	return addressable
+}
+
+// addTimestamps adds the times of an object to result.
+func addTimestamps(result map[string]interface{}, times client.Timestamps) map[string]interface{} {
+	result["created"] = times.Created
+	result["modified"] = times.Modified
+	result["origin"] = times.Origin
+	return result
+}
+
+func addressableResult(addressable client.Addressable) map[string]interface{} {
+	result := plain(maskAddressable(addressable)).(map[string]interface{})
+	result["type"] = transit.Keyword("addressable")
+	result["id"] = transit.Keyword(addressable.Id)
+	return result
+}
+
+// serviceOfDevice returns the service of a device, with its states as
+// keywords.
+func serviceOfDevice(service client.DeviceService) map[string]interface{} {
+	if service.Addressable != nil {
+		addressable := maskAddressable(*service.Addressable)
+		service.Addressable = &addressable
+	}
+	result := plain(service).(map[string]interface{})
+	result["adminState"] = transit.Keyword(service.AdminState)
+	result["operatingState"] = transit.Keyword(service.OperatingState)
+	return result
+}
+
+func deviceServiceResult(service client.DeviceService) map[string]interface{} {
+	result := serviceOfDevice(service)
+	result["type"] = transit.Keyword("device-service")
+	result["id"] = transit.Keyword(service.Id)
+	if addressable, ok := result["addressable"].(map[string]interface{}); ok {
+		addressable["id"] = transit.Keyword(service.Addressable.Id)
+	}
+	return result
+}
+
+// profileSummary returns the description of profile without its resources
+// and commands.
+func profileSummary(profile client.DeviceProfile) map[string]interface{} {
+	return addTimestamps(map[string]interface{}{
+		"id":           transit.Keyword(profile.Id),
+		"name":         profile.Name,
+		"description":  profile.Description,
+		"manufacturer": profile.Manufacturer,
+		"model":        profile.Model,
+		"labels":       profile.Labels,
+	}, profile.Timestamps)
+}
+
+func profileResult(profile client.DeviceProfile) map[string]interface{} {
+	result := profileSummary(profile)
+	result["type"] = transit.Keyword("device-profile")
+	result["deviceResources"] = plain(profile.DeviceResources)
+	result["resources"] = plain(profile.Resources)
+	result["commands"] = plain(profile.Commands)
+	return result
+}
+
+func deviceResult(device client.Device) map[string]interface{} {
+	result := addTimestamps(map[string]interface{}{
+		"type":           transit.Keyword("device"),
+		"id":             transit.Keyword(device.Id),
+		"name":           device.Name,
+		"description":    device.Description,
+		"labels":         device.Labels,
+		"adminState":     transit.Keyword(device.AdminState),
+		"operatingState": transit.Keyword(device.OperatingState),
+		"lastConnected":  device.LastConnected,
+		"lastReported":   device.LastReported,
+		"protocols":      plain(device.Protocols),
+		"autoEvents":     plain(device.AutoEvents),
+		"profile":        profileSummary(device.Profile),
+		"service":        serviceOfDevice(device.Service),
+	}, device.Timestamps)
+	if device.Addressable != nil {
+		result["addressable"] = plain(maskAddressable(*device.Addressable))
+	}
+	return result
+}
+
+func scheduleEventResult(event client.ScheduleEvent) map[string]interface{} {
+	result := addTimestamps(map[string]interface{}{
+		"type":        transit.Keyword("schedule-event"),
+		"id":          transit.Keyword(event.Id),
+		"name":        event.Name,
+		"schedule":    event.Schedule,
+		"parameters":  event.Parameters,
+		"service":     event.Service,
+		"addressable": plain(maskAddressable(event.Addressable)),
+	}, event.Timestamps)
+	result["addressable"].(map[string]interface{})["id"] = transit.Keyword(event.Addressable.Id)
+	return result
+}
+
+// orZero returns 0 for an unset start or end of a schedule.
+func orZero(time string) interface{} {
+	if time == "" {
+		return 0
+	}
+	return time
+}
+
+func intervalResult(interval client.Interval) map[string]interface{} {
+	return addTimestamps(map[string]interface{}{
+		"type":      transit.Keyword("schedule"),
+		"id":        transit.Keyword(interval.Id),
+		"name":      interval.Name,
+		"start":     orZero(interval.Start),
+		"end":       orZero(interval.End),
+		"frequency": interval.Frequency,
+		"cron":      interval.Cron,
+		"runOnce":   interval.RunOnce,
+	}, interval.Timestamps)
+}
+
+func intervalActionResult(action client.IntervalAction) map[string]interface{} {
+	action.Password = masked(action.Password)
+	result := plain(action).(map[string]interface{})
+	result["type"] = transit.Keyword("schedule-event")
+	result["id"] = transit.Keyword(action.Id)
+	return result
+}
+
+// readingValue decodes the value of a float reading, sent as the base64 of
+// its little endian bits: 8 chars for a float32 and 12 for a float64.
+func readingValue(value string) interface{} {
+	if (len(value) != 8 && len(value) != 12) || !strings.HasSuffix(value, "=") {
+		return value
+	}
+	bits, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return value
+	}
+	if len(bits) == 4 {
+		return math.Float32frombits(binary.LittleEndian.Uint32(bits))
+	}
+	if len(bits) == 8 {
+		return math.Float64frombits(binary.LittleEndian.Uint64(bits))
+	}
+	return value
+}
+
+func readingResult(reading client.Reading) map[string]interface{} {
+	return addTimestamps(map[string]interface{}{
+		"type":   transit.Keyword("reading"),
+		"id":     transit.Keyword(reading.Id),
+		"pushed": reading.Pushed,
+		"device": reading.Device,
+		"name":   reading.Name,
+		"value":  readingValue(reading.Value),
+	}, reading.Timestamps)
+}
+
+func valueDescriptorResult(descriptor client.ValueDescriptor) map[string]interface{} {
+	return addTimestamps(map[string]interface{}{
+		"type":         transit.Keyword("valuedescriptor"),
+		"id":           transit.Keyword(descriptor.Id),
+		"name":         descriptor.Name,
+		"description":  descriptor.Description,
+		"min":          descriptor.Min,
+		"max":          descriptor.Max,
+		"defaultValue": descriptor.DefaultValue,
+		"uomLabel":     descriptor.UomLabel,
+		"formatting":   descriptor.Formatting,
+		"labels":       descriptor.Labels,
+	}, descriptor.Timestamps)
+}
+
+// logResult returns a log entry with an id made of its creation time and
+// its position among the entries created at the same time.
+func logResult(entry client.LogEntry, id string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":          transit.Keyword("log-entry"),
+		"id":            transit.Keyword(id),
+		"originService": entry.OriginService,
+		"logLevel":      entry.LogLevel,
+		"labels":        entry.Labels,
+		"message":       entry.Message,
+		"created":       entry.Created,
+	}
+}
+
+func notificationResult(notification client.Notification) map[string]interface{} {
+	return addTimestamps(map[string]interface{}{
+		"type":        transit.Keyword("notification"),
+		"id":          transit.Keyword(notification.Id),
+		"slug":        notification.Slug,
+		"sender":      notification.Sender,
+		"category":    transit.Keyword(notification.Category),
+		"severity":    transit.Keyword(notification.Severity),
+		"content":     notification.Content,
+		"description": notification.Description,
+		"status":      transit.Keyword(notification.Status),
+		"labels":      notification.Labels,
+		"contenttype": notification.ContentType,
+	}, notification.Timestamps)
+}
+
+func transmissionResult(transmission client.Transmission) map[string]interface{} {
+	return addTimestamps(map[string]interface{}{
+		"type":         transit.Keyword("transmission"),
+		"id":           transit.Keyword(transmission.Id),
+		"notification": plain(transmission.Notification),
+		"receiver":     transmission.Receiver,
+		"channel":      plain(transmission.Channel),
+		"status":       transit.Keyword(transmission.Status),
+		"resendcount":  transmission.ResendCount,
+		"records":      plain(transmission.Records),
+	}, transmission.Timestamps)
+}
+
+func subscriptionResult(subscription client.Subscription) map[string]interface{} {
+	return addTimestamps(map[string]interface{}{
+		"type":                 transit.Keyword("subscription"),
+		"id":                   transit.Keyword(subscription.Id),
+		"slug":                 subscription.Slug,
+		"receiver":             subscription.Receiver,
+		"description":          subscription.Description,
+		"subscribedCategories": subscription.SubscribedCategories,
+		"subscribedLabels":     subscription.SubscribedLabels,
+		"channels":             plain(subscription.Channels),
+	}, subscription.Timestamps)
+}
+
+func exportResult(export client.Registration) map[string]interface{} {
+	return addTimestamps(map[string]interface{}{
+		"type":        transit.Keyword("export"),
+		"id":          transit.Keyword(export.Id),
+		"name":        export.Name,
+		"addressable": plain(maskAddressable(export.Addressable)),
+		"format":      transit.Keyword(export.Format),
+		"destination": transit.Keyword(export.Destination),
+		"compression": transit.Keyword(export.Compression),
+		"encryption": map[string]interface{}{
+			"encryptionAlgorithm": transit.Keyword(export.Encryption.EncryptionAlgorithm),
+			"encryptionKey":       masked(export.Encryption.EncryptionKey),
+			"initializingVector":  masked(export.Encryption.InitializingVector),
+		},
+		"filter": plain(export.Filter),
+		"enable": export.Enable,
+	}, export.Timestamps)
+}
@@ -5,122 +5,25 @@
 package edgex
 
 import (
-	"encoding/base64"
-	"encoding/binary"
-	"encoding/json"
-	"errors"
 	"fmt"
-	"io/ioutil"
-	"math"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/edgexfoundry/go-ui-server/internal/auth"
+	"github.com/edgexfoundry/go-ui-server/internal/edgex/client"
 	"github.com/edgexfoundry/go-ui-server/internal/fulcro"
+	"github.com/edgexfoundry/go-ui-server/internal/policy"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/russolsen/transit"
-
-	"golang.org/x/crypto/bcrypt"
-
-	"gopkg.in/resty.v1"
 )
 
-func Login(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
-	password := fulcro.GetString(args, "password")
-	pw_file := os.Getenv("DATA_FILE")
-	var existing []byte
-	if pw_file == "" {
-		fmt.Println("No Password File.")
-	  	return nil, errors.New("Password File Path Not defined.")
-	}
-
-	saved, err := ioutil.ReadFile(pw_file)
-	if err != nil {
-		// first login, file not exists
-		origin_pw := []byte("admin")
-		hashedBytes, err := bcrypt.GenerateFromPassword(origin_pw, bcrypt.DefaultCost)
-		existing = hashedBytes
-		if err != nil {
-			return  nil, err
-		}
-		err = ioutil.WriteFile(pw_file, hashedBytes, 0644)
-		if err != nil {
-			fmt.Println(err)
-			return  nil, err
-		}
-	} else {
-		expectedPassword := string(saved)
-		// remove last '\n' character of expectedPassword
-		expectedPassword = strings.TrimSuffix(expectedPassword, "\n")
-		existing = []byte(expectedPassword)
-	}
-	incoming := []byte(password)
-
-	if (bcrypt.CompareHashAndPassword(existing, incoming) != nil) {
-		return  nil, errors.New("Invalid Password")
-	}
-	session_id, _ := uuid.NewUUID()
-	result := map[transit.Keyword]string{transit.Keyword("session_id"): session_id.String()}
-
-	return result, nil
-}
-
-func ChangePassword(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
-	oldpw := fulcro.GetString(args, "oldpw")
-	newpw := fulcro.GetString(args, "newpw")
-	pw_file := os.Getenv("DATA_FILE")
-	var existing []byte
-	if pw_file == "" {
-		fmt.Println("Password File Path Not defined.")
-		return nil, errors.New("Password File Path Not defined.")
-	}
-	saved, err := ioutil.ReadFile(pw_file)
-	if err != nil {
-		// first login, file not exists
-		origin_pw := []byte("admin")
-		hashedBytes, err := bcrypt.GenerateFromPassword(origin_pw, bcrypt.DefaultCost)
-		existing = hashedBytes
-		if err != nil {
-			return  nil, err
-		}
-		err = ioutil.WriteFile(pw_file, hashedBytes, 0644)
-		if err != nil {
-			fmt.Println(err)
-			return  nil, err
-		}
-	} else {
-		expectedPassword := string(saved)
-		// remove last '\n' character of expectedPassword
-		expectedPassword = strings.TrimSuffix(expectedPassword, "\n")
-		existing = []byte(expectedPassword)
-	}
-	incoming := []byte(oldpw)
-	err = bcrypt.CompareHashAndPassword(existing, incoming)
-	if err != nil {
-		fmt.Println(err)
-		return  nil, errors.New("Invalid Current Password")
-	}
-
-	modifying := []byte(newpw)
-	hashedBytes, err := bcrypt.GenerateFromPassword(modifying, bcrypt.DefaultCost)
-	if err != nil {
-		return  nil, err
-	}
-	err = ioutil.WriteFile(pw_file, hashedBytes, 0644)
-	if err != nil {
-		fmt.Println(err)
-		return  nil, err
-	}
-	return nil, nil
-}
-
-func AddUpload(r *gin.Engine) {
+func AddUpload(r *gin.RouterGroup) {
 	var fileUpLoadId int64 = 0
 
-	r.POST("/file-uploads", func(c *gin.Context) {
+	r.POST("/file-uploads", fulcro.RequireRole(auth.RoleOperator), func(c *gin.Context) {
 		file, err := c.FormFile("file")
 		if err != nil {
 			c.String(http.StatusBadRequest, fmt.Sprintf("get form err: %s", err.Error()))
@@ -145,1039 +48,683 @@ func AddUpload(r *gin.Engine) {
 	})
 }
 
-func getDevices() (interface{}, error) {
-	var data []map[string]interface{}
-	var result interface{}
-
-	resp, err := resty.R().Get(getEndpoint(ClientMetadata) + "device")
-
-	if err == nil {
-		json.Unmarshal(resp.Body(), &data)
-		result = fulcro.AddType(data, "device")
-		result = fulcro.Remove(result, "profile", "deviceResources")
-		result = fulcro.Remove(result, "profile", "resources")
-		result = fulcro.Remove(result, "profile", "commands")
-		result = fulcro.MakeKeyword(result, "id")
-		result = fulcro.MakeKeyword(result, "adminState")
-		result = fulcro.MakeKeyword(result, "operatingState")
-		result = fulcro.MakeKeyword(result, "service", "adminState")
-		result = fulcro.MakeKeyword(result, "service", "operatingState")
-		result = fulcro.MakeKeyword(result, "profile", "id")
+func getDevices(ctx *fulcro.Context) (interface{}, error) {
+	devices, err := edgexClient(ctx).Devices()
+	if err != nil {
+		return nil, err
 	}
-	return result, err
+	result := make([]map[string]interface{}, len(devices))
+	for i, device := range devices {
+		result[i] = deviceResult(device)
+	}
+	return result, nil
 }
 
-func Devices(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
-	return fulcro.Keywordize(getDevices())
+func Devices(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+	return fulcro.Keywordize(getDevices(ctx))
 }
 
-func getDeviceServices() (interface{}, error) {
-	var data []map[string]interface{}
-	var result interface{}
-
-	resp, err := resty.R().Get(getEndpoint(ClientMetadata) + "deviceservice")
-
-	if err == nil {
-		json.Unmarshal(resp.Body(), &data)
-		result = fulcro.AddType(data, "device-service")
-		result = fulcro.MakeKeyword(result, "id")
-		result = fulcro.MakeKeyword(result, "adminState")
-		result = fulcro.MakeKeyword(result, "operatingState")
-		result = fulcro.MakeKeyword(result, "addressable", "id")
+func getDeviceServices(ctx *fulcro.Context) (interface{}, error) {
+	services, err := edgexClient(ctx).DeviceServices()
+	if err != nil {
+		return nil, err
 	}
-	return result, err
+	result := make([]map[string]interface{}, len(services))
+	for i, service := range services {
+		result[i] = deviceServiceResult(service)
+	}
+	return result, nil
 }
 
-func DeviceServices(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
-	return fulcro.Keywordize(getDeviceServices())
+func DeviceServices(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+	return fulcro.Keywordize(getDeviceServices(ctx))
 }
 
-func ScheduleEvents(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
-	var data []map[string]interface{}
-	var result interface{}
-
-	resp, err := resty.R().Get(getEndpoint(ClientMetadata) + "scheduleevent")
-
-	if err == nil {
-		json.Unmarshal(resp.Body(), &data)
-		result := fulcro.AddType(data, "schedule-event")
-		result = fulcro.MakeKeyword(result, "id")
-		result = fulcro.MakeKeyword(result, "adminState")
-		result = fulcro.MakeKeyword(result, "operatingState")
-		result = fulcro.MakeKeyword(result, "addressable", "id")
+func ScheduleEvents(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+	events, err := edgexClient(ctx).ScheduleEvents()
+	if err != nil {
+		return nil, err
 	}
-	return fulcro.Keywordize(result, err)
+	result := make([]map[string]interface{}, len(events))
+	for i, event := range events {
+		result[i] = scheduleEventResult(event)
+	}
+	return fulcro.Keywordize(result, nil)
 }
 
-func getAddressables() (interface{}, error) {
-	var data []map[string]interface{}
-	var result interface{}
-
-	resp, err := resty.R().Get(getEndpoint(ClientMetadata) + "addressable")
-
-	if err == nil {
-		json.Unmarshal(resp.Body(), &data)
-		result = fulcro.AddType(data, "addressable")
-		result = fulcro.MakeKeyword(result, "id")
+func getAddressables(ctx *fulcro.Context) (interface{}, error) {
+	addressables, err := edgexClient(ctx).Addressables()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, len(addressables))
+	for i, addressable := range addressables {
+		result[i] = addressableResult(addressable)
 	}
-	return result, err
+	return result, nil
 }
 
-func Addressables(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
-	return fulcro.Keywordize(getAddressables())
+func Addressables(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+	return fulcro.Keywordize(getAddressables(ctx))
 }
 
-func getProfiles() (interface{}, error) {
-	var data []map[string]interface{}
-	var result interface{}
-
-	resp, err := resty.R().Get(getEndpoint(ClientMetadata) + "deviceprofile")
-
-	if err == nil {
-		json.Unmarshal(resp.Body(), &data)
-		result = fulcro.AddType(data, "device-profile")
-		result = fulcro.MakeKeyword(result, "id")
-	}
-	return result, err
-}
-
-func doGet(getInfo interface{}) interface{} {
-	var data map[string]interface{}
-	var result [][2]string
-	info := getInfo.(map[string]interface{})
-	url := info["url"].(string)
-	resp, _ := resty.R().Get(url)
-	json.Unmarshal(resp.Body(), &data)
-	readings, _ := data["readings"]
-	rds := readings.([]interface{})
-	result = make([][2]string, len(rds))
-	for i, r := range rds {
-		reading := r.(map[string]interface{})
-		result[i] = [2]string{reading["name"].(string), fmt.Sprintf("%v", reading["value"])}
+func getProfiles(ctx *fulcro.Context) (interface{}, error) {
+	profiles, err := edgexClient(ctx).DeviceProfiles()
+	if err != nil {
+		return nil, err
 	}
-	return result
+	result := make([]map[string]interface{}, len(profiles))
+	for i, profile := range profiles {
+		result[i] = profileResult(profile)
+	}
+	return result, nil
 }
 
-func applyGets(data interface{}) interface{} {
-	commands := data.([]map[string]interface{})
-	count := 0
-	for i, cmd := range commands {
-		haveData := false
-		get, haveGet := cmd["get"]
-		if haveGet {
-			delete(commands[i], "get")
-			resp, haveResp := get.(map[string]interface{})["responses"]
-			if haveResp && resp != nil {
-				values := doGet(get)
-				commands[i]["value"] = values
-				count += len(values.([][2]string))
-				haveData = true
-			}
-		}
-		if !haveData {
-			dummyValue := make([][2]string, 1)
-			dummyValue[0] = [2]string{cmd["name"].(string), "N/A"}
-			commands[i]["value"] = dummyValue
-			count++
-		}
-	}
-	result := make([]map[string]interface{}, count)
-	pos := 0
-	for _, cmd := range commands {
-		values := cmd["value"].([][2]string)
-		for i, val := range values {
-			c := make(map[string]interface{})
-			for k, v := range cmd {
-				c[k] = v
+// commandValues returns the name and value of each reading of the get
+// command cmd of the device deviceId, or "N/A" if it cannot be read.
+func commandValues(c *client.Client, deviceId string, cmd client.Command) [][2]string {
+	if len(cmd.Get.Responses) > 0 {
+		event, err := c.IssueGetCommand(deviceId, cmd.Id)
+		if err == nil && len(event.Readings) > 0 {
+			values := make([][2]string, len(event.Readings))
+			for i, reading := range event.Readings {
+				values[i] = [2]string{reading.Name, reading.Value}
 			}
-			result[pos] = c
-			result[pos]["value"] = val
-			result[pos]["pos"] = i
-			result[pos]["size"] = len(values)
-			pos++
+			return values
 		}
 	}
-	return result
+	return [][2]string{{cmd.Name, "N/A"}}
 }
 
-func getCommands(id transit.Keyword) (interface{}, error) {
-	var data map[string]interface{}
-	var result interface{}
-
-	resp, err := resty.R().Get(getEndpoint(ClientCommand) + "device/" + string(id))
-
-	if err == nil {
-		json.Unmarshal(resp.Body(), &data)
-		commands := data["commands"].([]interface{})
-		result = make([]map[string]interface{}, len(commands))
-		for i, cmd := range commands {
-			result.([]map[string]interface{})[i] = cmd.(map[string]interface{})
-		}
-		result = fulcro.AddType(result, ClientCommand)
-		result = fulcro.MakeKeyword(result, "id")
-		result = applyGets(result)
-	}
-	return result, err
-}
-
-func Commands(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
-	return fulcro.Keywordize(getCommands(fulcro.GetKeyword(args, "id")))
-}
-
-func getReadingsInTimeRange(name string, from int64, to int64) (interface{}, error) {
-	const batchSize = 100
-	const maxRequests = 100
-	result := make([]interface{}, batchSize * maxRequests)
-	ids := make(map[string]bool)
-	pos := 0
-	limit := maxRequests
-	toStr := strconv.FormatInt(to, 10)
-	batchStr := strconv.FormatInt(batchSize, 10)
-	var count int
-	for ok := true; ok; ok = (count == batchSize) && (limit > 0) {
-		fromStr := strconv.FormatInt(from, 10)
-		resp, err := resty.R().Get(getEndpoint(ClientData) + "reading/" + fromStr + "/" + toStr + "/" + batchStr)
-		if err != nil {
-			return nil, err
-		}
-		var data []map[string]interface{}
-		json.Unmarshal(resp.Body(), &data)
-		readings := fulcro.AddType(data, "reading").([]map[string]interface{})
-		count = len(readings)
-		if (count > 0) {
-			from = int64(readings[count-1]["created"].(float64))
+// getCommands returns a row for each value of each command of the device
+// id, read with its get command, with the position of the value among those
+// of the command and the policy decision of the command.
+func getCommands(ctx *fulcro.Context, id transit.Keyword) (interface{}, error) {
+	c := edgexClient(ctx)
+	device, err := c.DeviceCommands(string(id))
+	if err != nil {
+		return nil, err
+	}
+	target, err := commandTargets(ctx, string(id))
+	if err != nil {
+		return nil, err
+	}
+	var result []map[string]interface{}
+	for _, cmd := range device.Commands {
+		values := commandValues(c, string(id), cmd)
+		for i, value := range values {
+			result = append(result, addTimestamps(map[string]interface{}{
+				"type":   transit.Keyword(ClientCommand),
+				"id":     transit.Keyword(cmd.Id),
+				"name":   cmd.Name,
+				"put":    plain(cmd.Put),
+				"policy": transit.Keyword(policy.Commands.Decide(target(cmd.Name))),
+				"value":  value,
+				"pos":    i,
+				"size":   len(values),
+			}, cmd.Timestamps))
 		}
-		for _, reading := range readings {
-			if reading["device"].(string) != name {
-				continue
-			}
+	}
+	return result, nil
+}
 
-			// check float32/float64 base64 encoding, should be a 8 or 12 chars long string
-			valueStr := reading["value"].(string)
-			valueLen := len(valueStr)
-			if (valueLen == 8 || valueLen == 12) && strings.HasSuffix(valueStr, "=") {
-				base64string := valueStr
-				decodeValue, _ := base64.StdEncoding.DecodeString(base64string)
-				if valueLen == 8 {
-					// handle float32 bits
-					bits := binary.LittleEndian.Uint32(decodeValue)
-					reading["value"] = math.Float32frombits(bits)
-				} else {
-					// handle float64 bits
-					bits := binary.LittleEndian.Uint64(decodeValue)
-					reading["value"] = math.Float64frombits(bits)
-				}
-			}
+func Commands(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+	return fulcro.Keywordize(getCommands(ctx, fulcro.GetKeyword(args, "id")))
+}
 
-			id := reading["id"].(string)
-			if !ids[id] {
-				ids[id] = true
-				result[pos] = fulcro.MakeKeyword(reading, "id")
-				pos++
-			}
-		}
-		limit--
+func getReadingsInTimeRange(ctx *fulcro.Context, name string, from int64, to int64) (interface{}, error) {
+	readings, err := edgexClient(ctx).DeviceReadings(name, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var result []map[string]interface{}
+	for _, reading := range readings {
+		result = append(result, readingResult(reading))
 	}
-	return result[:pos], nil
+	return result, nil
 }
 
-func DeviceReadings(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+func DeviceReadings(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
 	name := fulcro.GetString(args, "name")
 	from := fulcro.GetInt(args, "from")
 	to := fulcro.GetInt(args, "to")
-	return fulcro.Keywordize(getReadingsInTimeRange(name, from, to))
+	return fulcro.Keywordize(getReadingsInTimeRange(ctx, name, from, to))
 }
 
-func Profiles(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
-	return fulcro.Keywordize(getProfiles())
+func Profiles(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+	return fulcro.Keywordize(getProfiles(ctx))
 }
 
-func ProfileYaml(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
-	var result interface{}
-	var err error
-
+func ProfileYaml(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
 	id := fulcro.GetKeyword(args, "id")
-	resp, err := resty.R().Get(getEndpoint(ClientMetadata) + "deviceprofile/yaml/" + string(id))
-
-	if err == nil {
-		m := make(map[string]interface{})
-		m["yaml"] = resp.String()
-		arr := make([]interface{}, 1)
-		arr[0] = m
-		result = arr
+	yaml, err := edgexClient(ctx).DeviceProfileYaml(string(id))
+	if err != nil {
+		return nil, err
 	}
-	return fulcro.Keywordize(result, err)
+	return fulcro.Keywordize([]interface{}{map[string]interface{}{"yaml": yaml}}, nil)
 }
 
-func addDefault(data interface{}, keys ...string) interface{} {
-	schedules := data.([]map[string]interface{})
-	for _, s := range schedules {
-		for _, key := range keys {
-			v := s[key]
-			if v == nil {
-				s[key] = 0
-			}
-		}
+func getSchedules(ctx *fulcro.Context) (interface{}, error) {
+	intervals, err := edgexClient(ctx).Intervals()
+	if err != nil {
+		return nil, err
 	}
-	return schedules
-}
-
-func getSchedules() (interface{}, error) {
-	var result interface{}
-	var data []map[string]interface{}
-
-	resp, err := resty.R().Get(getEndpoint(ClientScheduler) + "interval")
-
-	if err == nil {
-		json.Unmarshal(resp.Body(), &data)
-		result = fulcro.AddType(data, "schedule")
-		result = fulcro.MakeKeyword(result, "id")
-		result = addDefault(result, "start", "end")
+	result := make([]map[string]interface{}, len(intervals))
+	for i, interval := range intervals {
+		result[i] = intervalResult(interval)
 	}
-	return result, err
+	return result, nil
 }
 
-func getScheduleEvents() (interface{}, error) {
-	var result interface{}
-	var data []map[string]interface{}
-
-	resp, err := resty.R().Get(getEndpoint(ClientScheduler) + "intervalaction")
-
-	if err == nil {
-		json.Unmarshal(resp.Body(), &data)
-		result = fulcro.AddType(data, "schedule-event")
-		result = fulcro.MakeKeyword(result, "id")
-		result = fulcro.MakeKeyword(result, "addressable", "id")
+func getScheduleEvents(ctx *fulcro.Context) (interface{}, error) {
+	actions, err := edgexClient(ctx).IntervalActions()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, len(actions))
+	for i, action := range actions {
+		result[i] = intervalActionResult(action)
 	}
-	return result, err
+	return result, nil
 }
 
-func ShowSchedules(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+func ShowSchedules(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
 	var err error
 	result := make(map[string]interface{})
-	result["content"], err = getSchedules()
+	result["content"], err = getSchedules(ctx)
 	if err == nil {
-		result["events"], err = getScheduleEvents()
+		result["events"], err = getScheduleEvents(ctx)
 	}
 	return fulcro.Keywordize(result, err)
 }
 
-func getNotifyInTimeRange(from int64, to int64, notifyType string, keys []string, slug string) (interface{}, error) {
-	const batchSize = 100
-	const maxRequests = 100
-	result := make([]interface{}, batchSize * maxRequests)
-	ids := make(map[transit.Keyword]bool)
-	pos := 0
-	limit := maxRequests
-	toStr := strconv.FormatInt(to, 10)
-	batchStr := strconv.FormatInt(batchSize, 10)
-	var count int
-	for ok := true; ok; ok = (count == batchSize) && (limit > 0) {
-		fromStr := strconv.FormatInt(from, 10)
-		var url string
-		if slug == "" {
-			url = getEndpoint(ClientNotifications) + notifyType + "/start/" + fromStr + "/end/" + toStr + "/" + batchStr
-		} else {
-			url = getEndpoint(ClientNotifications) + notifyType + "/slug/" + slug + "/start/" + fromStr + "/end/" + toStr + "/" + batchStr
-		}
-		resp, err := resty.R().Get(url)
-
-		if err != nil {
-			return nil, err
-		}
-
-		var data []map[string]interface{}
-		json.Unmarshal(resp.Body(), &data)
-		logs := fulcro.AddType(data, notifyType).([]map[string]interface{})
-		inc := 0
-		var last float64 = 0
-		for i, log := range logs {
-			ts := log["created"].(float64)
-			if ts != last {
-				inc = 0
-			}
-			for _, key := range keys {
-				if value, ok := log[key]; ok {
-					logs[i][key] = transit.Keyword(value.(string))
-				}
-			}
-			inc++
-			last = ts
-		}
-		count = len(logs)
-		if count > 0 {
-			from = int64(logs[count-1]["created"].(float64))
-			for _, entry := range logs {
-				id := entry["id"].(transit.Keyword)
-				if !ids[id] {
-					ids[id] = true
-					result[pos] = entry
-					pos++
-				}
-			}
-		}
-		limit--
+func getNotificationsInTimeRange(ctx *fulcro.Context, from int64, to int64) (interface{}, error) {
+	notifications, err := edgexClient(ctx).Notifications(from, to)
+	if err != nil {
+		return nil, err
 	}
-	return result[:pos], nil
+	var result []map[string]interface{}
+	for _, notification := range notifications {
+		result = append(result, notificationResult(notification))
+	}
+	return result, nil
 }
 
-func ShowNotifications(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+func ShowNotifications(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
 	result := make(map[string]interface{})
 	start := fulcro.GetInt(args, "start")
 	end := fulcro.GetInt(args, "end")
 	var err error
-	keys := []string{"id", "category", "severity", "status"}
-	result["content"], err = getNotifyInTimeRange(start, end, "notification", keys, "")
+	result["content"], err = getNotificationsInTimeRange(ctx, start, end)
 	return fulcro.Keywordize(result, err)
 }
 
-func ShowSubscriptions(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
-	var data []map[string]interface{}
-	result := make(map[string]interface{})
-	resp, err := resty.R().Get(getEndpoint(ClientNotifications) + "subscription")
+func ShowSubscriptions(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+	subscriptions, err := edgexClient(ctx).Subscriptions()
+	if err != nil {
+		return nil, err
+	}
+	content := make([]map[string]interface{}, len(subscriptions))
+	for i, subscription := range subscriptions {
+		content[i] = subscriptionResult(subscription)
+	}
+	return fulcro.Keywordize(map[string]interface{}{"content": content}, nil)
+}
 
-	if err == nil {
-		json.Unmarshal(resp.Body(), &data)
-		subscriptions := fulcro.AddType(data, "subscription")
-		subscriptions = fulcro.MakeKeyword(subscriptions, "id")
-		result["content"] = subscriptions
+// getTransmissionsInTimeRange returns the transmissions created from from to
+// to, only those of the notification slug unless it is empty.
+func getTransmissionsInTimeRange(ctx *fulcro.Context, from int64, to int64, slug string) (interface{}, error) {
+	c := edgexClient(ctx)
+	var transmissions []client.Transmission
+	var err error
+	if slug == "" {
+		transmissions, err = c.Transmissions(from, to)
+	} else {
+		transmissions, err = c.NotificationTransmissions(slug, from, to)
 	}
-	return fulcro.Keywordize(result, err)
+	if err != nil {
+		return nil, err
+	}
+	var result []map[string]interface{}
+	for _, transmission := range transmissions {
+		result = append(result, transmissionResult(transmission))
+	}
+	return result, nil
 }
 
-func ShowTransmissions(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+func ShowTransmissions(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
 	result := make(map[string]interface{})
 	slug := fulcro.GetString(args, "slug")
 	var start int64
 	var end int64
-  var err error
+	var err error
 
 	if slug != "" {
 		// show the transmissions from a week ago till now if get by slug
-		end = int64(time.Now().UnixNano())/int64(time.Millisecond)
-		start = int64(time.Now().AddDate(0, 0, -7).UnixNano())/int64(time.Millisecond)
+		end = int64(time.Now().UnixNano()) / int64(time.Millisecond)
+		start = int64(time.Now().AddDate(0, 0, -7).UnixNano()) / int64(time.Millisecond)
 	} else {
 		start = fulcro.GetInt(args, "start")
 		end = fulcro.GetInt(args, "end")
 	}
-	keys := []string{"id", "status"}
-	result["content"], err = getNotifyInTimeRange(start, end, "transmission", keys, slug)
+	result["content"], err = getTransmissionsInTimeRange(ctx, start, end, slug)
 	return fulcro.Keywordize(result, err)
 }
 
-func ShowExports(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
-	var data []map[string]interface{}
-	result := make(map[string]interface{})
-
-	resp, err := resty.R().Get(getEndpoint(ClientExport) + "registration")
-	if err == nil {
-		json.Unmarshal(resp.Body(), &data)
-		exports := fulcro.AddType(data, "export")
-		exports = fulcro.MakeKeyword(exports, "id")
-		exports = fulcro.MakeKeyword(exports, "destination")
-		exports = fulcro.MakeKeyword(exports, "format")
-		exports = fulcro.MakeKeyword(exports, "compression")
-		exports = fulcro.MakeKeyword(exports, "encryption", "encryptionAlgorithm")
-		result["content"] = exports
+func ShowExports(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+	exports, err := edgexClient(ctx).Registrations()
+	if err != nil {
+		return nil, err
 	}
-	return fulcro.Keywordize(result, err)
+	content := make([]map[string]interface{}, len(exports))
+	for i, export := range exports {
+		content[i] = exportResult(export)
+	}
+	return fulcro.Keywordize(map[string]interface{}{"content": content}, nil)
 }
 
-func ShowProfiles(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+func ShowProfiles(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
 	var err error
 	result := make(map[string]interface{})
-	result["content"], err = getProfiles()
+	result["content"], err = getProfiles(ctx)
 	return fulcro.Keywordize(result, err)
 }
 
-func ShowDevices(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+func ShowDevices(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
 	var err error
 	result := make(map[string]interface{})
-	result["content"], err = getDevices()
+	result["content"], err = getDevices(ctx)
 	if err == nil {
-		result["services"], err = getDeviceServices()
+		result["services"], err = getDeviceServices(ctx)
 	}
 	if err == nil {
-		result["profiles"], err = getProfiles()
+		result["profiles"], err = getProfiles(ctx)
 	}
 	return fulcro.Keywordize(result, err)
 }
 
-func ShowAddressables(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+func ShowAddressables(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
 	var err error
 	result := make(map[string]interface{})
-	result["content"], err = getAddressables()
+	result["content"], err = getAddressables(ctx)
 	return fulcro.Keywordize(result, err)
 }
 
-func getLogsInTimeRange(from int64, to int64) (interface{}, error) {
-	const batchSize = 100
-	const maxRequests = 100
-	result := make([]interface{}, batchSize * maxRequests)
-	ids := make(map[transit.Keyword]bool)
-	pos := 0
-	limit := maxRequests
-	toStr := strconv.FormatInt(to, 10)
-	batchStr := strconv.FormatInt(batchSize, 10)
-	var count int
-	for ok := true; ok; ok = (count == batchSize) && (limit > 0) {
-		fromStr := strconv.FormatInt(from, 10)
-		resp, err := resty.R().Get(getEndpoint(ClientLogging) + "logs/" + fromStr + "/" + toStr + "/" + batchStr)
-
-		if err != nil {
-			return nil, err
-		}
-
-		var data []map[string]interface{}
-		json.Unmarshal(resp.Body(), &data)
-		logs := fulcro.AddType(data, "log-entry").([]map[string]interface{})
-		inc := 0
-		var last float64 = 0
-		for i, log := range logs {
-			ts := log["created"].(float64)
-			if ts != last {
-				inc = 0
-			}
-			logs[i]["id"] = transit.Keyword(strconv.FormatInt(int64(ts), 10) + "-" + strconv.Itoa(inc))
-			inc++
-			last = ts
-		}
-		count = len(logs)
-		if count > 0 {
-			from = int64(logs[count-1]["created"].(float64))
-			for _, entry := range logs {
-				id := entry["id"].(transit.Keyword)
-				if !ids[id] {
-					ids[id] = true
-					result[pos] = entry
-					pos++
-				}
-			}
+// getLogsInTimeRange returns the log entries created from from to to. The
+// entries have no id, so they are numbered among those created at the same
+// time.
+func getLogsInTimeRange(ctx *fulcro.Context, from int64, to int64) (interface{}, error) {
+	logs, err := edgexClient(ctx).Logs(from, to)
+	if err != nil {
+		return nil, err
+	}
+	var result []map[string]interface{}
+	inc := 0
+	var last int64
+	for _, entry := range logs {
+		if entry.Created != last {
+			inc = 0
 		}
-		limit--
+		result = append(result, logResult(entry, strconv.FormatInt(entry.Created, 10)+"-"+strconv.Itoa(inc)))
+		inc++
+		last = entry.Created
 	}
-	return result[:pos], nil
+	return result, nil
 }
 
-func ShowLogs(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+func ShowLogs(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
 	var err error
 	start := fulcro.GetInt(args, "start")
 	end := fulcro.GetInt(args, "end")
 	result := make(map[string]interface{})
-	result["content"], err = getLogsInTimeRange(start, end)
+	result["content"], err = getLogsInTimeRange(ctx, start, end)
 	return fulcro.Keywordize(result, err)
 }
 
-func ShowCommands(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+func ShowCommands(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
 	var err error
 	id := fulcro.GetKeyword(args, "id")
 	result := make(map[string]interface{})
 	result["source-device"] = id
-	result["commands"], err = getCommands(id)
+	result["commands"], err = getCommands(ctx, id)
 	return fulcro.Keywordize(result, err)
 }
 
-func ReadingPage(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+func ReadingPage(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
 	var err error
 	result := make(map[string]interface{})
-	result["devices"], err = getDevices()
+	result["devices"], err = getDevices(ctx)
 	return fulcro.Keywordize(result, err)
 }
 
-func ValueDescriptors(params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
-	return fulcro.Keywordize(getValueDescriptors())
-}
-
-
-func getValueDescriptors() (interface{}, error) {
-	var data []map[string]interface{}
-	var result interface{}
-
-	resp, err := resty.R().Get(getEndpoint(ClientData) + "valuedescriptor")
-	if err == nil {
-		json.Unmarshal(resp.Body(), &data)
-		result = fulcro.AddType(data, "valuedescriptor")
-		result = fulcro.MakeKeyword(result, "id")
+func ValueDescriptors(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+	descriptors, err := edgexClient(ctx).ValueDescriptors()
+	if err != nil {
+		return nil, err
 	}
-	return fulcro.Keywordize(result, err)
+	result := make([]map[string]interface{}, len(descriptors))
+	for i, descriptor := range descriptors {
+		result[i] = valueDescriptorResult(descriptor)
+	}
+	return fulcro.Keywordize(result, nil)
 }
 
-func UpdateLockMode(args map[interface{}]interface{}) (interface{}, error) {
+func UpdateLockMode(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
 	id := fulcro.GetKeyword(args, "id")
 	mode := fulcro.GetKeyword(args, "mode")
-	device := Device{AdminState: string(mode)}
-	_, err := resty.R().SetBody(device).Put(getEndpoint(ClientMetadata) + "device/" + string(id))
+	err := edgexClient(ctx).UpdateDeviceAdminState(string(id), string(mode))
 	return id, err
 }
 
-func UploadProfile(args map[interface{}]interface{}) (interface{}, error) {
+func UploadProfile(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
 	fileId := fulcro.GetInt(args, "file-id")
 	fileName := "tmp-" + strconv.FormatInt(fileId, 10)
-	_, err := resty.R().
-		SetHeader("Content-Type", "application/x-yaml").
-		SetFile("file", fileName).
-		Post(getEndpoint(ClientMetadata) + "deviceprofile/uploadfile")
+	err := edgexClient(ctx).UploadDeviceProfile(fileName)
 	os.Remove(fileName)
 	return fileId, err
 }
 
-func DeleteProfile(args map[interface{}]interface{}) (interface{}, error) {
+func DeleteProfile(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
 	id := fulcro.GetKeyword(args, "id")
-	_, err := resty.R().Delete(getEndpoint(ClientMetadata) + "deviceprofile/id/" + string(id))
+	err := edgexClient(ctx).DeleteDeviceProfile(string(id))
 	return id, err
 }
 
-type Named struct {
-	Name string `json:"name"`
-}
-
-type Addressable struct {
-	Id        string `json:"id,omitempty"`
-	Name      string `json:"name,omitempty"`
-	Address   string `json:"address"`
-	Protocol  string `json:"protocol,omitempty"`
-	Port      int64  `json:"port,omitempty"`
-	Path      string `json:"path"`
-	Method    string `json:"method,omitempty"`
-	Publisher string `json:"publisher,omitempty"`
-	Topic     string `json:"topic,omitempty"`
-	User      string `json:"user,omitempty"`
-	Password  string `json:"password,omitempty"`
-	Cert      string `json:"cert,omitempty"`
-	Key       string `json:"key,omitempty"`
-}
-
-type Device struct {
-	Name           string   `json:"name"`
-	Description    string   `json:"description"`
-	Labels         []string `json:"labels"`
-	Profile        Named    `json:"profile"`
-	Service        Named    `json:"service"`
-	Addressable    Named    `json:"addressable"`
-	AdminState     string   `json:"adminState"`
-	OperatingState string   `json:"operatingState"`
-	Protocols      map[string]interface{} `json:"protocols"`
-	AutoEvents     []map[string]interface{} `json:"autoEvents"`
-}
-
-func AddDevice(args map[interface{}]interface{}) (interface{}, error) {
-	name := fulcro.GetString(args, "name")
-	description := fulcro.GetString(args, "description")
-	labels := fulcro.GetStringSeq(args, "labels")
-	profileName := fulcro.GetString(args, "profile-name")
-	serviceName := fulcro.GetString(args, "service-name")
-	protocols := fulcro.GetPrtsMap(args, "protocols")
-	auto_events := fulcro.GetMapStringSeq(args, "autoEvents")
-	device := Device{Name: name,
-		Description:    description,
-		Labels:         labels,
-		Profile:        Named{Name: profileName},
-		Service:        Named{Name: serviceName},
+// tempIdResult maps the tempid of the client to the id of the object added,
+// unless adding it failed.
+func tempIdResult(tempid transit.TaggedValue, id string, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	return fulcro.MkTempResult(tempid, transit.Keyword(id)), nil
+}
+
+func AddDevice(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
+	device := client.Device{
+		Name:           fulcro.GetString(args, "name"),
+		Description:    fulcro.GetString(args, "description"),
+		Labels:         fulcro.GetStringSeq(args, "labels"),
+		Profile:        client.DeviceProfile{Name: fulcro.GetString(args, "profile-name")},
+		Service:        client.DeviceService{Name: fulcro.GetString(args, "service-name")},
 		AdminState:     "UNLOCKED",
 		OperatingState: "ENABLED",
-		Protocols:       protocols,
-		AutoEvents:      auto_events,
+		Protocols:      getProtocols(args, "protocols"),
+		AutoEvents:     getAutoEvents(args, "autoEvents"),
 	}
-	_, err := resty.R().SetBody(device).Post(getEndpoint(ClientMetadata) + "device")
+	_, err := edgexClient(ctx).AddDevice(device)
 	return nil, err
 }
 
-func DeleteDevice(args map[interface{}]interface{}) (interface{}, error) {
-	id := fulcro.GetKeyword(args, "id")
-	_, err := resty.R().Delete(getEndpoint(ClientMetadata) + "device/id/" + string(id))
-	return id, err
-}
-
-func AddAddressable(args map[interface{}]interface{}) (interface{}, error) {
-	var result interface{}
-	tempid := fulcro.GetTempId(args, "tempid")
-	name := fulcro.GetString(args, "name")
-	address := fulcro.GetString(args, "address")
-	protocol := fulcro.GetString(args, "protocol")
-	port := fulcro.GetInt(args, "port")
-	path := fulcro.GetString(args, "path")
-	method := strings.ToUpper(string(fulcro.GetKeyword(args, "method")))
-	publisher := fulcro.GetString(args, "publisher")
-	topic := fulcro.GetString(args, "topic")
-	user := fulcro.GetString(args, "user")
-	password := fulcro.GetString(args, "password")
-	cert := fulcro.GetString(args, "cert")
-	key := fulcro.GetString(args, "key")
-	addressable := Addressable{
-		Id:        "",
-		Name:      name,
-		Address:   address,
-		Protocol:  protocol,
-		Port:      port,
-		Path:      path,
-		Method:    method,
-		Publisher: publisher,
-		Topic:     topic,
-		User:      user,
-		Password:  password,
-		Cert:      cert,
-		Key:       key,
-	}
-	resp, err := resty.R().SetBody(addressable).Post(getEndpoint(ClientMetadata) + "addressable")
-	if err == nil {
-		result = fulcro.MkTempIdResult(tempid, resp)
+// getProtocols returns the properties of each protocol of a device in the
+// argument id.
+func getProtocols(args map[interface{}]interface{}, id string) map[string]map[string]string {
+	result := make(map[string]map[string]string)
+	for name, properties := range fulcro.GetMap(args, id) {
+		protocol := make(map[string]string)
+		for key, value := range properties.(map[interface{}]interface{}) {
+			protocol[string(key.(transit.Keyword))] = value.(string)
+		}
+		result[string(name.(transit.Keyword))] = protocol
 	}
-	return result, err
+	return result
 }
 
-func EditAddressable(args map[interface{}]interface{}) (interface{}, error) {
-	id := fulcro.GetKeyword(args, "id")
-	address := fulcro.GetString(args, "address")
-	protocol := fulcro.GetString(args, "protocol")
-	port := fulcro.GetInt(args, "port")
-	path := fulcro.GetString(args, "path")
-	method := strings.ToUpper(string(fulcro.GetKeyword(args, "method")))
-	publisher := fulcro.GetString(args, "publisher")
-	topic := fulcro.GetString(args, "topic")
-	user := fulcro.GetString(args, "user")
-	password := fulcro.GetString(args, "password")
-	cert := fulcro.GetString(args, "cert")
-	key := fulcro.GetString(args, "key")
-	addressable := Addressable{
-		Id:        string(id),
-		Name:      "",
-		Address:   address,
-		Protocol:  protocol,
-		Port:      port,
-		Path:      path,
-		Method:    method,
-		Publisher: publisher,
-		Topic:     topic,
-		User:      user,
-		Password:  password,
-		Cert:      cert,
-		Key:       key,
-	}
-	_, err := resty.R().SetBody(addressable).Put(getEndpoint(ClientMetadata) + "addressable")
-	return id, err
+// getAutoEvents returns the auto events of a device in the argument id.
+func getAutoEvents(args map[interface{}]interface{}, id string) []client.AutoEvent {
+	var result []client.AutoEvent
+	for _, v := range args[transit.Keyword(id)].([]interface{}) {
+		event := v.(map[interface{}]interface{})
+		frequency, _ := event[transit.Keyword("frequency")].(string)
+		onChange, _ := event[transit.Keyword("onChange")].(bool)
+		resource, _ := event[transit.Keyword("resource")].(string)
+		result = append(result, client.AutoEvent{Frequency: frequency, OnChange: onChange, Resource: resource})
+	}
+	return result
 }
 
-func DeleteAddressable(args map[interface{}]interface{}) (interface{}, error) {
+func DeleteDevice(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
 	id := fulcro.GetKeyword(args, "id")
-	_, err := resty.R().Delete(getEndpoint(ClientMetadata) + "addressable/id/" + string(id))
+	err := edgexClient(ctx).DeleteDevice(string(id))
 	return id, err
 }
 
-type Schedule struct {
-	Name      string `json:"name,omitempty"`
-	Start     string `json:"start"`
-	End       string `json:"end"`
-	Frequency string `json:"frequency"`
-	RunOnce   bool   `json:"runOnce"`
+// getAddressable returns the addressable in args.
+func getAddressable(args map[interface{}]interface{}) client.Addressable {
+	return client.Addressable{
+		Address:   fulcro.GetString(args, "address"),
+		Protocol:  fulcro.GetString(args, "protocol"),
+		Port:      int(fulcro.GetInt(args, "port")),
+		Path:      fulcro.GetString(args, "path"),
+		Method:    strings.ToUpper(string(fulcro.GetKeyword(args, "method"))),
+		Publisher: fulcro.GetString(args, "publisher"),
+		Topic:     fulcro.GetString(args, "topic"),
+		User:      fulcro.GetString(args, "user"),
+		Password:  fulcro.GetString(args, "password"),
+		Cert:      fulcro.GetString(args, "cert"),
+		Key:       fulcro.GetString(args, "key"),
+	}
 }
 
-func AddSchedule(args map[interface{}]interface{}) (interface{}, error) {
-	var result interface{}
+func AddAddressable(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
 	tempid := fulcro.GetTempId(args, "tempid")
-	name := fulcro.GetString(args, "name")
-	start := fulcro.GetString(args, "start")
-	end := fulcro.GetString(args, "end")
-	frequency := fulcro.GetString(args, "frequency")
-	runOnce := fulcro.GetBool(args, "run-once")
-	schedule := Schedule{
-		Name:      name,
-		Start:     start,
-		End:       end,
-		Frequency: frequency,
-		RunOnce:   runOnce,
-	}
-	resp, err := resty.R().SetBody(schedule).Post(getEndpoint(ClientScheduler) + "interval")
-	if err == nil {
-		result = fulcro.MkTempIdResult(tempid, resp)
-	}
-	return result, err
+	addressable := getAddressable(args)
+	addressable.Name = fulcro.GetString(args, "name")
+	id, err := edgexClient(ctx).AddAddressable(addressable)
+	return tempIdResult(tempid, id, err)
 }
 
-func DeleteSchedule(args map[interface{}]interface{}) (interface{}, error) {
+func EditAddressable(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
 	id := fulcro.GetKeyword(args, "id")
-	_, err := resty.R().Delete(getEndpoint(ClientScheduler) + "interval/" + string(id))
+	c := edgexClient(ctx)
+	addressable := getAddressable(args)
+	addressable.Id = string(id)
+	if fulcro.IsMasked(addressable.Password) || fulcro.IsMasked(addressable.Cert) || fulcro.IsMasked(addressable.Key) {
+		current, err := c.Addressable(string(id))
+		if err != nil {
+			return nil, err
+		}
+		keepSecrets(&addressable, current)
+	}
+	err := c.UpdateAddressable(addressable)
 	return id, err
 }
 
-type ScheduleEvent struct {
-	Name        string `json:"name,omitempty"`
-	Parameters  string `json:"parameters"`
-	Interval    string `json:"interval"`
-	Target      string `json:"target"`
-	Address     string `json:"address"`
-	Protocol    string `json:"protocol,omitempty"`
-	Port        int64  `json:"port,omitempty"`
-	Path        string `json:"path"`
-	HTTPMethod  string `json:"httpMethod,omitempty"`
-	Publisher   string `json:"publisher,omitempty"`
-	Topic       string `json:"topic,omitempty"`
-	User        string `json:"user,omitempty"`
-	Password    string `json:"password,omitempty"`
-}
-
-func AddScheduleEvent(args map[interface{}]interface{}) (interface{}, error) {
-	var result interface{}
-	tempid := fulcro.GetTempId(args, "tempid")
-	name := fulcro.GetString(args, "name")
-	parameters := fulcro.GetString(args, "parameters")
-	interval := fulcro.GetString(args, "schedule-name")
-	target := fulcro.GetString(args, "target")
-	protocol := fulcro.GetString(args, "protocol")
-	httpMethod := strings.ToUpper(string(fulcro.GetKeyword(args, "httpMethod")))
-	address := fulcro.GetString(args, "address")
-	port := fulcro.GetInt(args, "port")
-	path := fulcro.GetString(args, "path")
-	publisher := fulcro.GetString(args, "publisher")
-	topic := fulcro.GetString(args, "topic")
-	user := fulcro.GetString(args, "user")
-	password := fulcro.GetString(args, "password")
-
-	scheduleEvent := ScheduleEvent{
-		Name:        name,
-		Parameters:  parameters,
-		Interval:    interval,
-		Target:     target,
-		Protocol:   protocol,
-		HTTPMethod: httpMethod,
-		Address:    address,
-		Port:       port,
-		Path:       path,
-		Publisher:  publisher,
-		Topic:      topic,
-		User:       user,
-		Password:   password,
-	}
-	resp, err := resty.R().SetBody(scheduleEvent).Post(getEndpoint(ClientScheduler) + "intervalaction")
-	if err == nil {
-		result = fulcro.MkTempIdResult(tempid, resp)
+// keepSecrets copies the credentials of current that the client left masked
+// into addressable.
+func keepSecrets(addressable *client.Addressable, current client.Addressable) {
+	if fulcro.IsMasked(addressable.Password) {
+		addressable.Password = current.Password
+	}
+	if fulcro.IsMasked(addressable.Cert) {
+		addressable.Cert = current.Cert
+	}
+	if fulcro.IsMasked(addressable.Key) {
+		addressable.Key = current.Key
 	}
-	return result, err
 }
 
-func DeleteScheduleEvent(args map[interface{}]interface{}) (interface{}, error) {
+func DeleteAddressable(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
 	id := fulcro.GetKeyword(args, "id")
-	_, err := resty.R().Delete(getEndpoint(ClientScheduler) + "intervalaction/" + string(id))
+	err := edgexClient(ctx).DeleteAddressable(string(id))
 	return id, err
 }
 
-type Encryption struct {
-	EncryptionAlgorithm string `json:"encryptionAlgorithm"`
-	EncryptionKey       string `json:"encryptionKey,omitempty"`
-	InitializingVector  string `json:"initializingVector,omitempty"`
+func AddSchedule(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
+	tempid := fulcro.GetTempId(args, "tempid")
+	interval := client.Interval{
+		Name:      fulcro.GetString(args, "name"),
+		Start:     fulcro.GetString(args, "start"),
+		End:       fulcro.GetString(args, "end"),
+		Frequency: fulcro.GetString(args, "frequency"),
+		RunOnce:   fulcro.GetBool(args, "run-once"),
+	}
+	id, err := edgexClient(ctx).AddInterval(interval)
+	return tempIdResult(tempid, id, err)
 }
 
-type Filter struct {
-	DeviceIdentifiers []string `json:"deviceIdentifiers"`
-	ValueDescriptorIdentifiers []string `json:"valueDescriptorIdentifiers"`
+func DeleteSchedule(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
+	id := fulcro.GetKeyword(args, "id")
+	err := edgexClient(ctx).DeleteInterval(string(id))
+	return id, err
 }
 
-type Export struct {
-	Id          string `json:"id,omitempty"`
-	Name        string `json:"name,omitempty"`
-	Addr        Addressable `json:"addressable"`
-	Format      string `json:"format"`
-	Destination string `json:"destination"`
-	Compression string `json:"compression"`
-	Encrypt     Encryption `json:"encryption"`
-	Filt        Filter `json:"filter"`
-	Enable      bool `json:"enable"`
+func AddScheduleEvent(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
+	tempid := fulcro.GetTempId(args, "tempid")
+	action := client.IntervalAction{
+		Name:       fulcro.GetString(args, "name"),
+		Parameters: fulcro.GetString(args, "parameters"),
+		Interval:   fulcro.GetString(args, "schedule-name"),
+		Target:     fulcro.GetString(args, "target"),
+		Protocol:   fulcro.GetString(args, "protocol"),
+		HTTPMethod: strings.ToUpper(string(fulcro.GetKeyword(args, "httpMethod"))),
+		Address:    fulcro.GetString(args, "address"),
+		Port:       int(fulcro.GetInt(args, "port")),
+		Path:       fulcro.GetString(args, "path"),
+		Publisher:  fulcro.GetString(args, "publisher"),
+		Topic:      fulcro.GetString(args, "topic"),
+		User:       fulcro.GetString(args, "user"),
+		Password:   fulcro.GetString(args, "password"),
+	}
+	id, err := edgexClient(ctx).AddIntervalAction(action)
+	return tempIdResult(tempid, id, err)
+}
+
+func DeleteScheduleEvent(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
+	id := fulcro.GetKeyword(args, "id")
+	err := edgexClient(ctx).DeleteIntervalAction(string(id))
+	return id, err
 }
 
-func AddExport(args map[interface{}]interface{}) (interface{}, error) {
-	var result interface{}
-	tempid := fulcro.GetTempId(args, "tempid")
-	name := fulcro.GetString(args, "name")
-	export := Export{
-		Id: "",
-		Name: name,
-		Addr: Addressable{
-			Id:        "",
-			Name:      name + "-addr",
-			Address:   fulcro.GetString(args, "address"),
-			Protocol:  fulcro.GetString(args, "protocol"),
-			Port:      fulcro.GetInt(args, "port"),
-			Path:      fulcro.GetString(args, "path"),
-			Method:    strings.ToUpper(string(fulcro.GetKeyword(args, "method"))),
-			Publisher: fulcro.GetString(args, "publisher"),
-			Topic:     fulcro.GetString(args, "topic"),
-			User:      fulcro.GetString(args, "user"),
-			Password:  fulcro.GetString(args, "password"),
-			Cert:      fulcro.GetString(args, "cert"),
-			Key:       fulcro.GetString(args, "key"),
-		},
+// getExport returns the export registration in args.
+func getExport(args map[interface{}]interface{}) client.Registration {
+	addressable := getAddressable(args)
+	addressable.Name = fulcro.GetString(args, "name") + "-addr"
+	return client.Registration{
+		Addressable: addressable,
 		Format:      fulcro.GetKeywordAsString(args, "format"),
 		Destination: fulcro.GetKeywordAsString(args, "destination"),
 		Compression: fulcro.GetKeywordAsString(args, "compression"),
-		Encrypt: Encryption{
+		Encryption: client.Encryption{
 			EncryptionAlgorithm: fulcro.GetKeywordAsString(args, "encryptionAlgorithm"),
 			EncryptionKey:       fulcro.GetString(args, "encryptionKey"),
 			InitializingVector:  fulcro.GetString(args, "initializingVector"),
 		},
-		Filt: Filter{
-			DeviceIdentifiers: fulcro.GetStringSeq(args, "device-filter"),
+		Filter: client.Filter{
+			DeviceIdentifiers:          fulcro.GetStringSeq(args, "device-filter"),
 			ValueDescriptorIdentifiers: fulcro.GetStringSeq(args, "reading-filter"),
 		},
 		Enable: fulcro.GetBool(args, "enable"),
 	}
-	resp, err := resty.R().SetBody(export).Post(getEndpoint(ClientExport) + "registration")
-	if err == nil {
-		result = fulcro.MkTempIdResult(tempid, resp)
-	}
-	return result, err
 }
 
-func EditExport(args map[interface{}]interface{}) (interface{}, error) {
+func AddExport(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
+	tempid := fulcro.GetTempId(args, "tempid")
+	export := getExport(args)
+	export.Name = fulcro.GetString(args, "name")
+	id, err := edgexClient(ctx).AddRegistration(export)
+	return tempIdResult(tempid, id, err)
+}
+
+func EditExport(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
 	id := fulcro.GetKeyword(args, "id")
-	name := fulcro.GetString(args, "name")
-	export := Export{
-		Id: string(id),
-		Name: "",
-		Addr: Addressable{
-			Id:        "",
-			Name:      name + "-addr",
-			Address:   fulcro.GetString(args, "address"),
-			Protocol:  fulcro.GetString(args, "protocol"),
-			Port:      fulcro.GetInt(args, "port"),
-			Path:      fulcro.GetString(args, "path"),
-			Method:    strings.ToUpper(string(fulcro.GetKeyword(args, "method"))),
-			Publisher: fulcro.GetString(args, "publisher"),
-			Topic:     fulcro.GetString(args, "topic"),
-			User:      fulcro.GetString(args, "user"),
-			Password:  fulcro.GetString(args, "password"),
-			Cert:      fulcro.GetString(args, "cert"),
-			Key:       fulcro.GetString(args, "key"),
-		},
-		Format:      fulcro.GetKeywordAsString(args, "format"),
-		Destination: fulcro.GetKeywordAsString(args, "destination"),
-		Compression: fulcro.GetKeywordAsString(args, "compression"),
-		Encrypt: Encryption{
-			EncryptionAlgorithm: fulcro.GetKeywordAsString(args, "encryptionAlgorithm"),
-			EncryptionKey:       fulcro.GetString(args, "encryptionKey"),
-			InitializingVector:  fulcro.GetString(args, "initializingVector"),
-		},
-		Filt: Filter{
-			DeviceIdentifiers: fulcro.GetStringSeq(args, "device-filter"),
-			ValueDescriptorIdentifiers: fulcro.GetStringSeq(args, "reading-filter"),
-		},
-		Enable: fulcro.GetBool(args, "enable"),
+	c := edgexClient(ctx)
+	export := getExport(args)
+	export.Id = string(id)
+	if hasMaskedSecrets(export) {
+		current, err := c.Registration(string(id))
+		if err != nil {
+			return nil, err
+		}
+		keepSecrets(&export.Addressable, current.Addressable)
+		if fulcro.IsMasked(export.Encryption.EncryptionKey) {
+			export.Encryption.EncryptionKey = current.Encryption.EncryptionKey
+		}
+		if fulcro.IsMasked(export.Encryption.InitializingVector) {
+			export.Encryption.InitializingVector = current.Encryption.InitializingVector
+		}
 	}
-	_, err := resty.R().SetBody(export).Put(getEndpoint(ClientExport) + "registration")
+	err := c.UpdateRegistration(export)
 	return id, err
 }
 
-func DeleteExport(args map[interface{}]interface{}) (interface{}, error) {
-	id := fulcro.GetKeyword(args, "id")
-	_, err := resty.R().Delete(getEndpoint(ClientExport) + "registration/id/" + string(id))
-	return id, err
+func hasMaskedSecrets(export client.Registration) bool {
+	return fulcro.IsMasked(export.Addressable.Password) || fulcro.IsMasked(export.Addressable.Cert) ||
+		fulcro.IsMasked(export.Addressable.Key) || fulcro.IsMasked(export.Encryption.EncryptionKey) ||
+		fulcro.IsMasked(export.Encryption.InitializingVector)
 }
 
-type Notification struct {
-	Id          string `json:"id,omitempty"`
-	Slug        string `json:"slug"`
-	Sender      string `json:"sender"`
-	Category    string `json:"category"`
-	Severity    string `json:"severity"`
-	Content     string `json:"content"`
-	Description string `json:"description,omitempty"`
-	Labels    []string `json:"labels"`
+func DeleteExport(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
+	id := fulcro.GetKeyword(args, "id")
+	err := edgexClient(ctx).DeleteRegistration(string(id))
+	return id, err
 }
 
-func AddNotification(args map[interface{}]interface{}) (interface{}, error) {
-	var result interface{}
+func AddNotification(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
 	tempid := fulcro.GetTempId(args, "tempid")
-	notify := Notification{
-		Id: "",
-		Slug: fulcro.GetString(args, "slug"),
+	notification := client.Notification{
+		Slug:        fulcro.GetString(args, "slug"),
 		Description: fulcro.GetString(args, "description"),
-		Sender: fulcro.GetString(args, "sender"),
-		Category: fulcro.GetKeywordAsString(args, "category"),
-		Severity: fulcro.GetKeywordAsString(args, "severity"),
-		Content: fulcro.GetString(args,"content"),
-		Labels: fulcro.GetStringSeq(args, "labels"),
-	}
-	resp, err := resty.R().SetBody(notify).Post(getEndpoint(ClientNotifications) + "notification")
-	if err == nil {
-		result = fulcro.MkTempIdResult(tempid, resp)
+		Sender:      fulcro.GetString(args, "sender"),
+		Category:    fulcro.GetKeywordAsString(args, "category"),
+		Severity:    fulcro.GetKeywordAsString(args, "severity"),
+		Content:     fulcro.GetString(args, "content"),
+		Labels:      fulcro.GetStringSeq(args, "labels"),
 	}
-	return result, err
+	id, err := edgexClient(ctx).AddNotification(notification)
+	return tempIdResult(tempid, id, err)
 }
 
-func DeleteNotification(args map[interface{}]interface{}) (interface{}, error) {
+func DeleteNotification(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
 	slug := fulcro.GetString(args, "slug")
-	_, err := resty.R().Delete(getEndpoint(ClientNotifications) + "notification/slug/" + slug)
+	err := edgexClient(ctx).DeleteNotification(slug)
 	return slug, err
 }
 
-type Channel struct {
-	Type            string `json:"type,omitempty"`  // REST or EMAIL
-	MailAddresses []string    `json:"mailAddresses,omitempty"`
-	Url             string `json:"url,omitempty"`
-}
-
-type Subscription struct {
-	Id                     string `json:"id,omitempty"`
-	Slug                   string `json:"slug"`
-	Receiver               string `json:"receiver"`
-	Description            string `json:"description,omitempty"`
-	SubscribedCategories []string `json:"subscribedCategories,omitempty"`
-	SubscribedLabels     []string `json:"subscribedLabels,omitempty"`
-	Channels             []interface{} `json:"channels"`
+// getSubscription returns the subscription in args.
+func getSubscription(args map[interface{}]interface{}) client.Subscription {
+	return client.Subscription{
+		Slug:                 fulcro.GetString(args, "slug"),
+		Description:          fulcro.GetString(args, "description"),
+		Receiver:             fulcro.GetString(args, "receiver"),
+		SubscribedCategories: fulcro.GetStringSeq(args, "subscribedCategories"),
+		SubscribedLabels:     fulcro.GetStringSeq(args, "subscribedLabels"),
+		Channels:             getChannelSeq(args, "channels"),
+	}
 }
 
-func AddSubscription(args map[interface{}]interface{}) (interface{}, error) {
-	var result interface{}
+// AddSubscription adds a subscription and looks it up by its slug for its
+// id, which the notifications service does not return.
+func AddSubscription(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
 	tempid := fulcro.GetTempId(args, "tempid")
-	slug := fulcro.GetString(args, "slug")
-
-	subscription := Subscription{
-		Id: "",
-		Slug: slug,
-		Description: fulcro.GetString(args, "description"),
-		Receiver: fulcro.GetString(args, "receiver"),
-		SubscribedCategories: fulcro.GetStringSeq(args, "subscribedCategories"),
-		SubscribedLabels: fulcro.GetStringSeq(args, "subscribedLabels"),
-		Channels: getChannelSeq(args, "channels"),
-	}
-	_, err := resty.R().SetBody(subscription).Post(getEndpoint(ClientNotifications) + "subscription")
-	if err == nil {
-		resp, err := resty.R().Get(getEndpoint(ClientNotifications) + "subscription/slug/" + slug)
-		if err == nil {
-			var data map[string]interface{}
-			json.Unmarshal(resp.Body(), &data)
-			id := data["id"].(string)
-			result = fulcro.MkTempResult(tempid, transit.Keyword(id))
-		}
+	c := edgexClient(ctx)
+	subscription := getSubscription(args)
+	if err := c.AddSubscription(subscription); err != nil {
+		return nil, err
 	}
-	return result, err
+	added, err := c.Subscription(subscription.Slug)
+	return tempIdResult(tempid, added.Id, err)
 }
 
-func EditSubscription(args map[interface{}]interface{}) (interface{}, error) {
+func EditSubscription(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
 	id := fulcro.GetKeyword(args, "id")
-	subscription := Subscription{
-		Id: string(id),
-		Slug: fulcro.GetString(args, "slug"),
-		Description: fulcro.GetString(args, "description"),
-		Receiver: fulcro.GetString(args, "receiver"),
-		SubscribedCategories: fulcro.GetStringSeq(args, "subscribedCategories"),
-		SubscribedLabels: fulcro.GetStringSeq(args, "subscribedLabels"),
-		Channels: getChannelSeq(args, "channels"),
-	}
-	_, err := resty.R().SetBody(subscription).Put(getEndpoint(ClientNotifications) + "subscription")
+	subscription := getSubscription(args)
+	subscription.Id = string(id)
+	err := edgexClient(ctx).UpdateSubscription(subscription)
 	return id, err
 }
 
-func DeleteSubscription(args map[interface{}]interface{}) (interface{}, error) {
+func DeleteSubscription(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
 	slug := fulcro.GetString(args, "slug")
-	_, err := resty.R().Delete(getEndpoint(ClientNotifications) + "subscription/slug/" + slug)
+	err := edgexClient(ctx).DeleteSubscription(slug)
 	return slug, err
 }
 
-func getChannelSeq(args map[interface{}]interface{}, id string) []interface{} {
+func getChannelSeq(args map[interface{}]interface{}, id string) []client.Channel {
 	outer := args[transit.Keyword(id)].([]interface{})
-	result := make([]interface{}, len(outer))
+	result := make([]client.Channel, len(outer))
 	for i, s := range outer {
 		seq := s.(map[interface{}]interface{})
-		var channel Channel
+		var channel client.Channel
 		for key, v := range seq {
 			switch key {
 			case transit.Keyword("type"):
@@ -1190,7 +737,6 @@ func getChannelSeq(args map[interface{}]interface{}, id string) []interface{} {
 				for j, email := range arr {
 					emails[j] = email.(string)
 				}
-				//channel.MailAddresses = v.([]string)
 				channel.MailAddresses = emails
 			}
 		}
@@ -1199,26 +745,85 @@ func getChannelSeq(args map[interface{}]interface{}, id string) []interface{} {
 	return result
 }
 
-func getValueSeq(args map[interface{}]interface{}, id string) [][]interface{} {
-	outer := args[transit.Keyword(id)].([]interface{})
+// getValueSeq returns the argument id, a vector of vectors, or a 400 error
+// if it is anything else.
+func getValueSeq(args map[interface{}]interface{}, id string) ([][]interface{}, error) {
+	outer, ok := args[transit.Keyword(id)].([]interface{})
+	if !ok {
+		return nil, &fulcro.Error{Status: http.StatusBadRequest, Message: "Missing or invalid " + id}
+	}
 	result := make([][]interface{}, len(outer))
 	for i, s := range outer {
-		seq := s.([]interface{})
+		seq, ok := s.([]interface{})
+		if !ok {
+			return nil, &fulcro.Error{Status: http.StatusBadRequest, Message: fmt.Sprintf("Invalid %s %v", id, s)}
+		}
 		result[i] = make([]interface{}, len(seq))
 		for j, v := range seq {
 			result[i][j] = v
 		}
 	}
-	return result
+	return result, nil
 }
 
-func IssueSetCommand(args map[interface{}]interface{}) (interface{}, error) {
-	url := fulcro.GetString(args, "url")
-	values := getValueSeq(args, "values")
+// getCommand looks up a command of a device in the command service.
+func getCommand(ctx *fulcro.Context, deviceId string, commandId string) (client.DeviceCommands, client.Command, error) {
+	device, err := edgexClient(ctx).DeviceCommands(deviceId)
+	if err != nil {
+		return device, client.Command{}, err
+	}
+	for _, cmd := range device.Commands {
+		if cmd.Id == commandId {
+			return device, cmd, nil
+		}
+	}
+	return device, client.Command{}, &fulcro.Error{Status: http.StatusNotFound, Message: "Unknown command " + commandId}
+}
+
+// IssueSetCommand sends the values to the put command of a device. Only the
+// parameters the command declares may be set, and only if the command policy
+// allows it.
+func IssueSetCommand(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
+	deviceId := string(fulcro.GetKeyword(args, "device"))
+	commandId := string(fulcro.GetKeyword(args, "command"))
+	values, err := getValueSeq(args, "values")
+	if err != nil {
+		return nil, err
+	}
+	_, cmd, err := getCommand(ctx, deviceId, commandId)
+	if err != nil {
+		return nil, err
+	}
+	if len(cmd.Put.ParameterNames) == 0 {
+		return nil, &fulcro.Error{Status: http.StatusBadRequest, Message: "Command " + cmd.Name + " cannot be set"}
+	}
+	allowed := make(map[string]bool, len(cmd.Put.ParameterNames))
+	for _, name := range cmd.Put.ParameterNames {
+		allowed[name] = true
+	}
 	data := make(map[string]interface{}, len(values))
 	for _, v := range values {
-		data[v[0].(string)] = v[2]
+		// each value is sent as [name type value]
+		if len(v) < 3 {
+			return nil, &fulcro.Error{Status: http.StatusBadRequest,
+				Message: fmt.Sprintf("Invalid value %v of command %s", v, cmd.Name)}
+		}
+		name, _ := v[0].(string)
+		if !allowed[name] {
+			return nil, &fulcro.Error{Status: http.StatusBadRequest,
+				Message: "Command " + cmd.Name + " has no parameter " + name}
+		}
+		data[name] = v[2]
+	}
+	confirmed, _ := args[transit.Keyword("confirmed")].(bool)
+	ticket, err := checkCommandPolicy(ctx, deviceId, cmd.Name, confirmed)
+	if err != nil {
+		return nil, err
+	}
+	// a command that failed after it was sent may still have been carried
+	// out, so it keeps counting against the rate limits
+	if err = edgexClient(ctx).IssueSetCommand(deviceId, commandId, data); client.NotSent(err) {
+		policy.Commands.Release(ticket)
 	}
-	_, err := resty.R().SetBody(data).Put(url)
 	return nil, err
 }
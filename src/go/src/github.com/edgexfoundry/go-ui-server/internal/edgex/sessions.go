@@ -0,0 +1,64 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package edgex
+
+import (
+	"errors"
+	"time"
+
+	"github.com/edgexfoundry/go-ui-server/internal/auth"
+	"github.com/edgexfoundry/go-ui-server/internal/fulcro"
+	"github.com/russolsen/transit"
+)
+
+// cookies scopes the cookies set by the server to its base path
+var cookies = auth.CookieOptions{Path: "/"}
+
+// InitCookies sets the path and Secure flag of the cookies from config.
+func InitCookies(config *Config) {
+	cookies = auth.CookieOptions{Path: config.Server.Base(), Secure: config.Server.SecureCookies}
+}
+
+// millis returns t in milliseconds since the epoch, 0 if t is not set.
+func millis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func Logout(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
+	if ctx.Session != nil {
+		auth.Sessions.Delete(ctx.Session.Token)
+	}
+	auth.ClearSessionCookie(ctx.Writer, ctx.Request, cookies)
+	return nil, nil
+}
+
+// Sessions lists the sessions of the logged in user.
+func Sessions(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+	sessions := auth.Sessions.List(ctx.Session.User, ctx.Session.Provider)
+	result := make([]map[string]interface{}, len(sessions))
+	for i, session := range sessions {
+		result[i] = map[string]interface{}{
+			"type":        transit.Keyword("session"),
+			"id":          transit.Keyword(session.Handle),
+			"remote-addr": session.RemoteAddr,
+			"user-agent":  session.UserAgent,
+			"created":     millis(session.Created),
+			"last-seen":   millis(session.LastSeen),
+			"current":     session.Handle == ctx.Session.Handle,
+		}
+	}
+	return fulcro.Keywordize(result, nil)
+}
+
+func RevokeSession(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
+	id := fulcro.GetKeyword(args, "id")
+	if !auth.Sessions.Revoke(ctx.Session.User, ctx.Session.Provider, string(id)) {
+		return nil, errors.New("Unknown session")
+	}
+	return id, nil
+}
@@ -0,0 +1,138 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package edgex
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/edgexfoundry/go-ui-server/internal/edgex/client"
+	"github.com/edgexfoundry/go-ui-server/internal/fulcro"
+	"gopkg.in/resty.v1"
+)
+
+// The settings of a client that are not configured
+const (
+	defaultTimeout        = 10 * time.Second
+	defaultConnectTimeout = 3 * time.Second
+	defaultRetryWait      = 200 * time.Millisecond
+	defaultRetryMaxWait   = 2 * time.Second
+)
+
+// contextKey marks the fulcro context of the UI request in the context of
+// the requests made for it
+type contextKey struct{}
+
+func init() {
+	resty.DefaultClient.OnAfterResponse(recordStatus)
+	resty.DefaultClient.SetTimeout(defaultTimeout)
+}
+
+// edgexClient returns the client of the EdgeX services making requests on
+// behalf of ctx, using the TLS, token, timeout and retry settings of each
+// service. The requests are cancelled when the browser's request is.
+func edgexClient(ctx *fulcro.Context) *client.Client {
+	return client.New(requestContext(ctx), registry)
+}
+
+// requestContext returns the context of the requests made on behalf of ctx.
+func requestContext(ctx *fulcro.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return context.WithValue(ctx.Request.Context(), contextKey{}, ctx)
+}
+
+// recordStatus keeps the status of a response from an EdgeX service in the
+// context of the UI request it was made for.
+func recordStatus(client *resty.Client, resp *resty.Response) error {
+	if ctx, ok := resp.Request.Context().Value(contextKey{}).(*fulcro.Context); ok {
+		ctx.UpstreamStatus = resp.StatusCode()
+	}
+	return nil
+}
+
+// orDefault returns the setting ms in milliseconds, or def if it is not set.
+func orDefault(ms int, def time.Duration) time.Duration {
+	if ms <= 0 {
+		return def
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+func (info ClientInfo) timeout() time.Duration {
+	return orDefault(info.Timeout, defaultTimeout)
+}
+
+func (info ClientInfo) connectTimeout() time.Duration {
+	return orDefault(info.ConnectTimeout, defaultConnectTimeout)
+}
+
+func (info ClientInfo) retry() client.Retry {
+	return client.Retry{
+		Count:   info.Retries,
+		Wait:    orDefault(info.RetryWait, defaultRetryWait),
+		MaxWait: orDefault(info.RetryMaxWait, defaultRetryMaxWait),
+	}
+}
+
+// newClient returns a REST client that trusts the configured CA bundle,
+// presents the client certificate and sends the bearer token of info, and
+// gives up on requests that take longer than its timeouts.
+func newClient(info ClientInfo) (*resty.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   info.connectTimeout(),
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	client := resty.New()
+	client.OnAfterResponse(recordStatus)
+	if info.protocol() == "https" {
+		tlsConfig := &tls.Config{InsecureSkipVerify: info.InsecureSkipVerify}
+		if info.CAFile != "" {
+			pem, err := ioutil.ReadFile(info.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("could not read CA bundle (%s): %v", info.CAFile, err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in CA bundle (%s)", info.CAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		if info.CertFile != "" || info.KeyFile != "" {
+			if info.CertFile == "" || info.KeyFile == "" {
+				return nil, fmt.Errorf("both CertFile and KeyFile must be set")
+			}
+			cert, err := tls.LoadX509KeyPair(info.CertFile, info.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("could not load client certificate: %v", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	client.SetTransport(transport)
+	client.SetTimeout(info.timeout())
+	token := info.Token
+	if info.TokenFile != "" {
+		contents, err := ioutil.ReadFile(info.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read token file (%s): %v", info.TokenFile, err)
+		}
+		token = strings.TrimSpace(string(contents))
+	}
+	if token != "" {
+		client.SetAuthToken(token)
+	}
+	return client, nil
+}
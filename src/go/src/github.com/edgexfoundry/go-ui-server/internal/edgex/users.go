@@ -0,0 +1,173 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package edgex
+
+import (
+	"net/http"
+
+	"github.com/edgexfoundry/go-ui-server/internal/auth"
+	"github.com/edgexfoundry/go-ui-server/internal/fulcro"
+	"github.com/russolsen/transit"
+)
+
+// userName returns the user named in args, defaulting to the logged in user
+// and then to the default admin for clients that only send a password.
+func userName(ctx *fulcro.Context, args map[interface{}]interface{}) string {
+	name := fulcro.GetString(args, "username")
+	if name == "" && ctx.Session != nil {
+		name = ctx.Session.User
+	}
+	if name == "" {
+		name = auth.DefaultUser
+	}
+	return name
+}
+
+// checkLogin returns an error if the client is locked out after failed logins,
+// and otherwise holds its login slot until Failed, Succeeded or Done.
+func checkLogin(ctx *fulcro.Context, name string) error {
+	if err := auth.Logins.Check(ctx.RemoteIP()); err != nil {
+		auth.Security.Log(auth.EventLoginLockedOut, name, ctx.RemoteIP(), err.Error())
+		return &fulcro.Error{Status: http.StatusTooManyRequests, Message: err.Error()}
+	}
+	return nil
+}
+
+func Login(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+	if err := localLoginDisabled(); err != nil {
+		return nil, err
+	}
+	password := fulcro.GetString(args, "password")
+	name := userName(ctx, args)
+	if err := checkLogin(ctx, name); err != nil {
+		return nil, err
+	}
+	user, err := auth.Users.Authenticate(name, password)
+	if err != nil {
+		if err == auth.ErrInvalidCredentials {
+			auth.Logins.Failed(ctx.RemoteIP())
+		} else {
+			auth.Logins.Done(ctx.RemoteIP())
+		}
+		auth.Security.Log(auth.EventLoginFailed, name, ctx.RemoteIP(), err.Error())
+		return nil, err
+	}
+	auth.Logins.Succeeded(ctx.RemoteIP())
+	session, err := auth.Sessions.Create(user.Name, user.MustChange, ctx.Request)
+	if err != nil {
+		return nil, err
+	}
+	auth.Security.Log(auth.EventLoginSucceeded, user.Name, ctx.RemoteIP(), "")
+	auth.SetSessionCookie(ctx.Writer, ctx.Request, session, cookies)
+	// the token itself stays in the HttpOnly cookie, the client only needs
+	// to know that it is logged in
+	result := map[transit.Keyword]interface{}{
+		transit.Keyword("session_id"): session.Handle,
+		transit.Keyword("username"):   user.Name,
+		transit.Keyword("role"):       transit.Keyword(user.Role.String()),
+		transit.Keyword("restricted"): session.Restricted,
+	}
+	return result, nil
+}
+
+func ChangePassword(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+	if err := localLoginDisabled(); err != nil {
+		return nil, err
+	}
+	oldpw := fulcro.GetString(args, "oldpw")
+	newpw := fulcro.GetString(args, "newpw")
+	name := userName(ctx, args)
+	if err := checkLogin(ctx, name); err != nil {
+		return nil, err
+	}
+	if err := auth.Users.ChangePassword(name, oldpw, newpw); err != nil {
+		if err == auth.ErrInvalidCurrentPassword {
+			auth.Logins.Failed(ctx.RemoteIP())
+		} else {
+			auth.Logins.Done(ctx.RemoteIP())
+		}
+		auth.Security.Log(auth.EventPasswordNotChanged, name, ctx.RemoteIP(), err.Error())
+		return nil, err
+	}
+	auth.Logins.Done(ctx.RemoteIP())
+	auth.Security.Log(auth.EventPasswordChanged, name, ctx.RemoteIP(), "")
+	auth.Sessions.Unrestrict(name)
+	return nil, nil
+}
+
+func Users(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+	users, err := auth.Users.List()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, len(users))
+	for i, user := range users {
+		result[i] = map[string]interface{}{
+			"type":     transit.Keyword("user"),
+			"id":       transit.Keyword(user.Name),
+			"name":     user.Name,
+			"role":     transit.Keyword(user.Role.String()),
+			"disabled": user.Disabled,
+		}
+	}
+	return fulcro.Keywordize(result, nil)
+}
+
+func CreateUser(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
+	name := fulcro.GetString(args, "name")
+	role, err := auth.ParseRole(fulcro.GetKeywordAsString(args, "role"))
+	if err != nil {
+		return nil, err
+	}
+	return transit.Keyword(name), auth.Users.Create(name, fulcro.GetString(args, "password"), role)
+}
+
+func DisableUser(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
+	name := fulcro.GetString(args, "name")
+	disabled := fulcro.GetBool(args, "disabled")
+	if err := auth.Users.SetDisabled(name, disabled); err != nil {
+		return nil, err
+	}
+	if disabled {
+		auth.Sessions.RevokeUser(name)
+	}
+	return transit.Keyword(name), nil
+}
+
+func ResetUserPassword(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
+	name := fulcro.GetString(args, "name")
+	if err := auth.Users.ResetPassword(name, fulcro.GetString(args, "password")); err != nil {
+		return nil, err
+	}
+	auth.Sessions.RevokeUser(name)
+	return transit.Keyword(name), nil
+}
+
+// LoginLockouts lists the clients with recent failed logins.
+func LoginLockouts(ctx *fulcro.Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+	clients := auth.Logins.List()
+	result := make([]map[string]interface{}, len(clients))
+	for i, client := range clients {
+		result[i] = map[string]interface{}{
+			"type":         transit.Keyword("login-lockout"),
+			"id":           transit.Keyword(client.Client),
+			"client":       client.Client,
+			"failures":     client.Failures,
+			"last-failure": millis(client.LastFailure),
+			"until":        millis(client.Until),
+			"locked":       client.Locked,
+		}
+	}
+	return fulcro.Keywordize(result, nil)
+}
+
+// ClearLoginLockout forgets the failed logins of a client, or of all clients
+// if none is given.
+func ClearLoginLockout(ctx *fulcro.Context, args map[interface{}]interface{}) (interface{}, error) {
+	client := fulcro.GetString(args, "client")
+	auth.Logins.Clear(client)
+	auth.Security.Log(auth.EventLockoutCleared, ctx.Session.User, ctx.RemoteIP(), client)
+	return transit.Keyword(client), nil
+}
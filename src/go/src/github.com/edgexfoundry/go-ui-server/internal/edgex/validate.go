@@ -0,0 +1,186 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package edgex
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/edgexfoundry/go-ui-server/internal/oidc"
+	"github.com/edgexfoundry/go-ui-server/internal/policy"
+)
+
+// ConfigError lists every problem found in a configuration file.
+type ConfigError struct {
+	Path     string
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid configuration (%s):\n  %s", e.Path, strings.Join(e.Problems, "\n  "))
+}
+
+func validPort(port int) bool {
+	return port >= 1 && port <= 65535
+}
+
+func validProtocol(protocol string) bool {
+	protocol = strings.ToLower(protocol)
+	return protocol == "" || protocol == "http" || protocol == "https"
+}
+
+func validAPIVersion(version string) bool {
+	version = strings.ToLower(version)
+	return version == "" || version == APIv1 || version == APIv2
+}
+
+// ValidateConfig returns the problems of config: clients missing or not
+// known, ports out of range, unknown protocols and API versions, negative
+// numbers such as timeouts, and settings rejected by the packages using them.
+func ValidateConfig(config *Config) []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if !validPort(config.Server.Port) {
+		add("Server.Port: %d is not a port (1-65535)", config.Server.Port)
+	}
+	if err := config.Server.TLSConfig.Validate(); err != nil {
+		add("Server: %v", err)
+	}
+	if err := config.Server.WebConfig.Validate(); err != nil {
+		add("Server: %v", err)
+	}
+	if err := config.Server.SecurityConfig.Validate(); err != nil {
+		add("Server: %v", err)
+	}
+	if config.Server.RedirectPort != 0 {
+		if !validPort(config.Server.RedirectPort) {
+			add("Server.RedirectPort: %d is not a port (1-65535)", config.Server.RedirectPort)
+		} else if config.Server.RedirectPort == config.Server.Port {
+			add("Server.RedirectPort: must differ from Server.Port")
+		}
+	}
+	if err := config.Password.HashConfig.Validate(); err != nil {
+		add("Password: %v", err)
+	}
+	if config.OIDC.Enabled {
+		if _, err := oidc.New(config.OIDC); err != nil {
+			add("OIDC: %v", err)
+		}
+	}
+	if _, err := policy.New(config.CommandPolicy); err != nil {
+		add("CommandPolicy: %v", err)
+	}
+
+	known := make(map[string]bool, len(clientNames))
+	for _, name := range clientNames {
+		known[name] = true
+		if _, ok := config.Clients[name]; !ok {
+			add("Clients.%s: missing", name)
+		}
+	}
+	for name, info := range config.Clients {
+		path := "Clients." + name
+		if !known[name] {
+			add("%s: unknown client", path)
+			continue
+		}
+		if info.Host == "" {
+			add("%s.Host: missing", path)
+		}
+		if !validPort(info.Port) {
+			add("%s.Port: %d is not a port (1-65535)", path, info.Port)
+		}
+		if !validAPIVersion(info.APIVersion) {
+			add("%s.APIVersion: %q is neither %s nor %s", path, info.APIVersion, APIv1, APIv2)
+		}
+		if !validProtocol(info.Protocol) {
+			add("%s.Protocol: %q is neither http nor https", path, info.Protocol)
+			continue
+		}
+		if _, err := newClient(info); err != nil {
+			add("%s: %v", path, err)
+		}
+	}
+
+	if config.Registry.Enabled {
+		if config.Registry.Port != 0 && !validPort(config.Registry.Port) {
+			add("Registry.Port: %d is not a port (1-65535)", config.Registry.Port)
+		}
+		if !validProtocol(config.Registry.Protocol) {
+			add("Registry.Protocol: %q is neither http nor https", config.Registry.Protocol)
+		}
+	}
+	for name, service := range config.Registry.Services {
+		if !known[name] {
+			add("Registry.Services.%s: unknown client", name)
+		} else if service == "" {
+			add("Registry.Services.%s: missing service name", name)
+		}
+	}
+
+	negativeSettings(reflect.ValueOf(config).Elem(), "", &problems)
+	sort.Strings(problems)
+	return problems
+}
+
+// negativeSettings adds a problem for each number below v that is negative.
+// No number in the configuration, be it a timeout or a count, may be. Ports
+// are left out as their range is checked already.
+func negativeSettings(v reflect.Value, name string, problems *[]string) {
+	join := func(field string) string {
+		if name == "" {
+			return field
+		}
+		return name + "." + field
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				negativeSettings(v.Field(i), name, problems)
+				continue
+			}
+			negativeSettings(v.Field(i), join(field.Name), problems)
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			negativeSettings(v.MapIndex(key), join(key.String()), problems)
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			for i := 0; i < v.Len(); i++ {
+				negativeSettings(v.Index(i), fmt.Sprintf("%s[%d]", name, i), problems)
+			}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < 0 && !strings.HasSuffix(name, "Port") {
+			*problems = append(*problems, fmt.Sprintf("%s: %d must not be negative", name, v.Int()))
+		}
+	}
+}
+
+// checkConfig returns a ConfigError listing the unknown keys of the file at
+// path and the problems of config, nil if there are none.
+func checkConfig(path string, config *Config, unknown []string) error {
+	var problems []string
+	for _, key := range unknown {
+		problems = append(problems, key+": unknown setting")
+	}
+	problems = append(problems, ValidateConfig(config)...)
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ConfigError{Path: path, Problems: problems}
+}
@@ -0,0 +1,246 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package fulcro
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultContentSecurityPolicy allows the manager's own scripts, including
+// the inline start up call, and the fonts and styles it loads from CDNs.
+const DefaultContentSecurityPolicy = "default-src 'self'; " +
+	"script-src 'self' 'unsafe-inline'; " +
+	"style-src 'self' 'unsafe-inline' maxcdn.bootstrapcdn.com fonts.googleapis.com; " +
+	"font-src 'self' data: maxcdn.bootstrapcdn.com fonts.gstatic.com; " +
+	"img-src 'self' data:; " +
+	"connect-src 'self'; " +
+	"frame-ancestors 'none'"
+
+// SecurityConfig sets the headers protecting the browser and which origins
+// may send requests that change state.
+type SecurityConfig struct {
+	// AllowedOrigins lists origins, such as "https://manager.example.com",
+	// accepted in addition to the server's own origin
+	AllowedOrigins []string
+	// ContentSecurityPolicy is the Content-Security-Policy header,
+	// DefaultContentSecurityPolicy if not set and none if "-"
+	ContentSecurityPolicy string
+	// FrameOptions is the X-Frame-Options header, "DENY" if not set and none
+	// if "-"
+	FrameOptions string
+	// ReferrerPolicy is the Referrer-Policy header, "same-origin" if not set
+	// and none if "-"
+	ReferrerPolicy string
+	// HSTSMaxAge is the max-age in seconds of the Strict-Transport-Security
+	// header sent over HTTPS, none if 0
+	HSTSMaxAge            int
+	HSTSIncludeSubdomains bool
+	// SecureCookies marks the cookies HTTPS only even when served over plain
+	// HTTP, for a reverse proxy terminating TLS
+	SecureCookies bool
+	// TrustedProxies lists the addresses or networks, such as "10.0.0.0/8",
+	// of reverse proxies whose forwarding headers name the client
+	TrustedProxies []string
+}
+
+func headerValue(value string, def string) string {
+	switch value {
+	case "":
+		return def
+	case "-":
+		return ""
+	default:
+		return value
+	}
+}
+
+// SecurityHeaders returns middleware that adds the configured security
+// headers to every response.
+func SecurityHeaders(config SecurityConfig) gin.HandlerFunc {
+	headers := map[string]string{
+		"Content-Security-Policy": headerValue(config.ContentSecurityPolicy, DefaultContentSecurityPolicy),
+		"X-Frame-Options":         headerValue(config.FrameOptions, "DENY"),
+		"Referrer-Policy":         headerValue(config.ReferrerPolicy, "same-origin"),
+		"X-Content-Type-Options":  "nosniff",
+	}
+	hsts := ""
+	if config.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(config.HSTSMaxAge)
+		if config.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		for name, value := range headers {
+			if value != "" {
+				h.Set(name, value)
+			}
+		}
+		if hsts != "" && c.Request.TLS != nil {
+			h.Set("Strict-Transport-Security", hsts)
+		}
+		c.Next()
+	}
+}
+
+// SameOrigin returns middleware that rejects requests changing state which a
+// browser sent on behalf of a page from another origin. The Origin header,
+// or the Referer if there is none, has to match the host the request was
+// sent to or one of the allowed origins. Requests carrying neither are not
+// from a browser page and are let through.
+func SameOrigin(config SecurityConfig) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(config.AllowedOrigins))
+	for _, origin := range config.AllowedOrigins {
+		allowed[strings.TrimSuffix(strings.ToLower(origin), "/")] = true
+	}
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		source := c.Request.Header.Get("Origin")
+		if source == "" {
+			source = c.Request.Header.Get("Referer")
+		}
+		if source == "" {
+			c.Next()
+			return
+		}
+		u, err := url.Parse(source)
+		if err != nil || u.Host == "" {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		origin := strings.ToLower(u.Scheme + "://" + u.Host)
+		if !strings.EqualFold(u.Host, c.Request.Host) && !allowed[origin] {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+// ClientAddress returns middleware that replaces the remote address of
+// requests from one of the trusted proxies with the client address they
+// forwarded, taken from the Forwarded header or else from X-Forwarded-For.
+// The addresses are read from the right, skipping further trusted proxies,
+// as only the entries added by those can be believed.
+func ClientAddress(config SecurityConfig) gin.HandlerFunc {
+	trusted, _ := parseProxies(config.TrustedProxies)
+	return func(c *gin.Context) {
+		if len(trusted) > 0 {
+			c.Request.RemoteAddr = forwardedFor(c.Request, trusted)
+		}
+		c.Next()
+	}
+}
+
+// Validate checks that the trusted proxies are addresses or networks.
+func (c SecurityConfig) Validate() error {
+	_, err := parseProxies(c.TrustedProxies)
+	return err
+}
+
+func parseProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, fmt.Errorf("TrustedProxies: %q is not an address or network", p)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("TrustedProxies: %q is not an address or network", p)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func isTrusted(ip net.IP, trusted []*net.IPNet) bool {
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor returns the client address of a request, following the
+// forwarding headers back as long as they were added by trusted proxies.
+func forwardedFor(r *http.Request, trusted []*net.IPNet) string {
+	client := peerAddress(r.RemoteAddr)
+	ip := net.ParseIP(client)
+	if ip == nil || !isTrusted(ip, trusted) {
+		return client
+	}
+	hops := forwardedHeader(r.Header.Values("Forwarded"))
+	if hops == nil {
+		hops = forwardedHeader(r.Header.Values("X-Forwarded-For"))
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hops[i])
+		if ip == nil {
+			// unknown or obfuscated, the last proxy is all we know
+			break
+		}
+		client = ip.String()
+		if !isTrusted(ip, trusted) {
+			break
+		}
+	}
+	return client
+}
+
+// forwardedHeader returns the addresses listed by the values of either the
+// Forwarded header, from its for parameters, or X-Forwarded-For, without
+// ports and brackets. Hops without an address are kept as empty strings.
+func forwardedHeader(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			addr := strings.TrimSpace(element)
+			if strings.Contains(addr, "=") {
+				addr = ""
+				for _, pair := range strings.Split(element, ";") {
+					kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+					if len(kv) == 2 && strings.EqualFold(kv[0], "for") {
+						addr = strings.Trim(kv[1], `"`)
+					}
+				}
+			}
+			if host, _, err := net.SplitHostPort(addr); err == nil {
+				addr = host
+			}
+			hops = append(hops, strings.Trim(addr, "[]"))
+		}
+	}
+	return hops
+}
+
+func peerAddress(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
@@ -7,65 +7,211 @@ package fulcro
 import (
 	"container/list"
 	"fmt"
+	"io/fs"
 	"net/http"
-	"strings"
+	"os"
+	"time"
 
+	"github.com/edgexfoundry/go-ui-server/internal/audit"
+	"github.com/edgexfoundry/go-ui-server/internal/auth"
 	"github.com/gin-gonic/gin"
 	"github.com/russolsen/transit"
 )
 
-type QueryFunc func(params []interface{}, args map[interface{}]interface{}) (interface{}, error)
+// Context carries the state of the current request to query and mutation
+// functions. Session is nil when the client is not logged in.
+type Context struct {
+	*gin.Context
+	Session *auth.Session
+	// UpstreamStatus is the HTTP status of the last call to an EdgeX
+	// service made for the request
+	UpstreamStatus int
+}
+
+type QueryFunc func(ctx *Context, params []interface{}, args map[interface{}]interface{}) (interface{}, error)
+
+type MutationFunc func(ctx *Context, args map[interface{}]interface{}) (interface{}, error)
+
+// Error is an error reported to the client with a specific HTTP status.
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
 
-type MutationFunc func(args map[interface{}]interface{}) (interface{}, error)
+// detailed is implemented by errors that tell the client more than their
+// message, such as the service and status of a failed call to EdgeX. The
+// details are added to the error reply next to :message.
+type detailed interface {
+	Details() map[string]interface{}
+}
+
+var (
+	ErrUnauthorized = &Error{Status: http.StatusUnauthorized, Message: "Not logged in"}
+	ErrForbidden    = &Error{Status: http.StatusForbidden, Message: "Permission denied"}
+	// ErrPasswordChange is returned to sessions logged in with a default or
+	// reset password for everything but the password change
+	ErrPasswordChange = &Error{Status: http.StatusForbidden, Message: "Password change required"}
+)
+
+const sessionKey = "session"
 
 type Server struct {
 	handlers map[transit.Keyword]QueryFunc
 	mutators map[transit.Symbol]MutationFunc
+	roles    map[interface{}]auth.Role
 }
 
 func NewServer() Server {
 	return Server{
 		handlers: make(map[transit.Keyword]QueryFunc),
 		mutators: make(map[transit.Symbol]MutationFunc),
+		roles:    make(map[interface{}]auth.Role),
 	}
 }
 
-func (s Server) AddQueryFunc(k string, f QueryFunc) {
+// AddQueryFunc registers the query k, which may only be run by users with at
+// least the given role. Queries open to everybody, such as the login itself,
+// use auth.RoleNone.
+func (s Server) AddQueryFunc(k string, role auth.Role, f QueryFunc) {
 	key := transit.Keyword(k)
 	s.handlers[key] = f
+	s.roles[key] = role
 }
 
-func (s Server) InvokeQueryFunc(key transit.Keyword, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
+func (s Server) InvokeQueryFunc(ctx *Context, key transit.Keyword, params []interface{}, args map[interface{}]interface{}) (interface{}, error) {
 	var result interface{} = nil
 	var err error = nil
 	f, ok := s.handlers[key]
 	if ok {
-		result, err = f(params, args)
+		if err = authorize(ctx, s.roles[key]); err != nil {
+			return nil, err
+		}
+		result, err = f(ctx, params, args)
 	}
 	return result, err
 }
 
-func (s Server) AddMutationFunc(key transit.Symbol, f MutationFunc) {
+// AddMutationFunc registers the mutation key, which may only be run by users
+// with at least the given role.
+func (s Server) AddMutationFunc(key transit.Symbol, role auth.Role, f MutationFunc) {
 	s.mutators[key] = f
+	s.roles[key] = role
 }
 
-func (s Server) InvokeMutatorFunc(key transit.Symbol, args map[interface{}]interface{}) (interface{}, error) {
+// InvokeMutatorFunc runs the mutation key and records it in the audit log.
+func (s Server) InvokeMutatorFunc(ctx *Context, key transit.Symbol, args map[interface{}]interface{}) (interface{}, error) {
 	var result interface{}
 	var err error
 	f, ok := s.mutators[key]
 	if ok {
-		result, err = f(args)
+		ctx.UpstreamStatus = 0
+		if err = authorize(ctx, s.roles[key]); err == nil {
+			result, err = f(ctx, args)
+		}
+		recordMutation(ctx, key, args, err)
 	}
 	return result, err
 }
 
-func (s Server) rootQuery(query map[interface{}]interface{}, args map[interface{}]interface{}, result *transit.CMap) error {
+func recordMutation(ctx *Context, key transit.Symbol, args map[interface{}]interface{}, err error) {
+	entry := audit.Entry{
+		Time:     time.Now().UTC(),
+		Client:   ctx.RemoteIP(),
+		Mutation: string(key),
+		Args:     audit.Sanitize(args),
+		Status:   ctx.UpstreamStatus,
+	}
+	if ctx.Session != nil {
+		entry.User = ctx.Session.User
+		entry.Session = ctx.Session.Handle
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if e := audit.Trail.Record(entry); e != nil {
+		fmt.Fprintf(os.Stderr, "could not write audit log: %v\n", e)
+	}
+}
+
+func authorize(ctx *Context, role auth.Role) error {
+	if role == auth.RoleNone {
+		return nil
+	}
+	if ctx.Session == nil {
+		return ErrUnauthorized
+	}
+	if ctx.Session.Restricted {
+		return ErrPasswordChange
+	}
+	if ctx.Session.Provider != "" {
+		if ctx.Session.Role < role {
+			return ErrForbidden
+		}
+		return nil
+	}
+	switch auth.Users.Authorize(ctx.Session.User, role) {
+	case nil:
+		return nil
+	case auth.ErrForbidden:
+		return ErrForbidden
+	default:
+		return ErrUnauthorized
+	}
+}
+
+// LoadSession is middleware that attaches the client's session, if any, to
+// the request.
+func LoadSession(c *gin.Context) {
+	if session, ok := auth.Sessions.Get(auth.SessionToken(c.Request)); ok {
+		c.Set(sessionKey, session)
+	}
+	c.Next()
+}
+
+// RequireRole returns middleware that rejects requests without a live
+// session of a user with at least the given role.
+func RequireRole(role auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get(sessionKey); !ok {
+			if session, ok := auth.Sessions.Get(auth.SessionToken(c.Request)); ok {
+				c.Set(sessionKey, session)
+			}
+		}
+		if err := authorize(NewContext(c), role); err != nil {
+			c.AbortWithStatus(err.(*Error).Status)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RemoteIP returns the address of the client. Forwarding headers are only
+// followed for connections from the trusted proxies, see ClientAddress, as
+// anybody else can set them.
+func (ctx *Context) RemoteIP() string {
+	return peerAddress(ctx.Request.RemoteAddr)
+}
+
+// NewContext wraps a gin request together with its session.
+func NewContext(c *gin.Context) *Context {
+	ctx := &Context{Context: c}
+	if session, ok := c.Get(sessionKey); ok {
+		ctx.Session = session.(*auth.Session)
+	}
+	return ctx
+}
+
+func (s Server) rootQuery(ctx *Context, query map[interface{}]interface{}, args map[interface{}]interface{}, result *transit.CMap) error {
 	var err error
 	for k, p := range query {
 		var val interface{}
 		key := k.(transit.Keyword)
 		params := p.([]interface{})
-		val, err = s.InvokeQueryFunc(key, params, args)
+		val, err = s.InvokeQueryFunc(ctx, key, params, args)
 		if err != nil {
 			break
 		}
@@ -74,21 +220,21 @@ func (s Server) rootQuery(query map[interface{}]interface{}, args map[interface{
 	return err
 }
 
-func (s Server) mutation(op *list.List, result map[transit.Symbol]interface{}) error {
+func (s Server) mutation(ctx *Context, op *list.List, result map[transit.Symbol]interface{}) error {
 	var err error
 	key := op.Front().Value.(transit.Symbol)
 	args := op.Front().Next().Value.(map[interface{}]interface{})
-	result[key], err = s.InvokeMutatorFunc(key, args)
+	result[key], err = s.InvokeMutatorFunc(ctx, key, args)
 	return err
 }
 
-func (s Server) entityQuery(query *transit.CMap, args map[interface{}]interface{}, result *transit.CMap) error {
+func (s Server) entityQuery(ctx *Context, query *transit.CMap, args map[interface{}]interface{}, result *transit.CMap) error {
 	var err error
 	for _, e := range query.Entries {
 		var val interface{}
 		key := e.Key.([]interface{})[0].(transit.Keyword)
 		params := e.Value.([]interface{})
-		val, err = s.InvokeQueryFunc(key, params, args)
+		val, err = s.InvokeQueryFunc(ctx, key, params, args)
 		if err != nil {
 			break
 		}
@@ -97,42 +243,23 @@ func (s Server) entityQuery(query *transit.CMap, args map[interface{}]interface{
 	return err
 }
 
-func SPAFile(group *gin.Engine, relativePaths []string, filepath string) {
-	api := group.Group("/")
-	handler := func(c *gin.Context) {
-		id := c.Param("id")
-		if id == "" {
-			c.File(filepath)
-		} else {
-			fmt.Println("id", id)
-			switch strings.Split(id, "/")[1] {
-			case "js", "css", "img", "fonts":
-				id = "./assets" + id
-				fmt.Println("id now", id)
-				c.File(id)
-			default:
-				c.File(filepath)
-			}
-		}
-	}
-	for _, relativePath := range relativePaths {
-		subgroup := api.Group(relativePath)
-		{
-			subgroup.GET("", handler)	
-		}
-	}
-}
-
-func (s Server) SetupRouter() *gin.Engine {
+// SetupRouter returns the router serving the API and the web client with
+// the given security headers and cross-origin checks, and the group of
+// routes below the base path of web. The web client is served from assets
+// unless web sets an asset directory.
+func (s Server) SetupRouter(security SecurityConfig, web WebConfig, assets fs.FS) (*gin.Engine, *gin.RouterGroup) {
 	gin.DisableConsoleColor()
 	r := gin.Default()
+	r.Use(ClientAddress(security), SecurityHeaders(security), SameOrigin(security))
+	base := r.Group(web.Base())
 
 	// Ping test
-	r.GET("/ping", func(c *gin.Context) {
+	base.GET("/ping", func(c *gin.Context) {
 		c.String(http.StatusOK, "pong")
 	})
 
-	r.POST("/api", func(c *gin.Context) {
+	base.POST("/api", LoadSession, func(c *gin.Context) {
+		ctx := NewContext(c)
 		req := make([]interface{}, 0)
 		var result interface{} = nil
 		decoder := transit.NewDecoder(c.Request.Body)
@@ -147,12 +274,12 @@ func (s Server) SetupRouter() *gin.Engine {
 						if result == nil {
 							result = transit.NewCMap()
 						}
-						err = s.rootQuery(t, nil, result.(*transit.CMap))
+						err = s.rootQuery(ctx, t, nil, result.(*transit.CMap))
 					case *transit.CMap:
 						if result == nil {
 							result = transit.NewCMap()
 						}
-						err = s.entityQuery(t, nil, result.(*transit.CMap))
+						err = s.entityQuery(ctx, t, nil, result.(*transit.CMap))
 					case *list.List:
 						switch head := t.Front().Value.(type) {
 						case map[interface{}]interface{}:
@@ -160,28 +287,40 @@ func (s Server) SetupRouter() *gin.Engine {
 								result = transit.NewCMap()
 							}
 							args := t.Front().Next().Value.(map[interface{}]interface{})
-							err = s.rootQuery(head, args, result.(*transit.CMap))
+							err = s.rootQuery(ctx, head, args, result.(*transit.CMap))
 						case *transit.CMap:
 							if result == nil {
 								result = transit.NewCMap()
 							}
 							args := t.Front().Next().Value.(map[interface{}]interface{})
-							err = s.entityQuery(head, args, result.(*transit.CMap))
+							err = s.entityQuery(ctx, head, args, result.(*transit.CMap))
 						default:
 							if result == nil {
 								result = make(map[transit.Symbol]interface{})
 							}
-							err = s.mutation(t, result.(map[transit.Symbol]interface{}))
+							err = s.mutation(ctx, t, result.(map[transit.Symbol]interface{}))
 						}
 					default:
 						fmt.Printf("unknown query %v %T\n", t, t)
 					}
+					if err != nil {
+						break
+					}
 				}
 				if err != nil {
+					status := http.StatusBadGateway
+					if e, ok := err.(*Error); ok {
+						status = e.Status
+					}
 					errResult := make(map[transit.Keyword]interface{})
+					if e, ok := err.(detailed); ok {
+						for k, v := range e.Details() {
+							errResult[transit.Keyword(k)] = v
+						}
+					}
 					errResult[transit.Keyword("message")] = err.Error()
 					result = errResult
-					c.Render(http.StatusBadGateway, Transit{Data: result})
+					c.Render(status, Transit{Data: result})
 				} else if result != nil {
 					c.Render(http.StatusOK, Transit{Data: result})
 				} else {
@@ -193,30 +332,7 @@ func (s Server) SetupRouter() *gin.Engine {
 		}
 	})
 
-	var paths = []string {
-		"/",
-		"/info/*id",
-		"/command/*id",
-		"/reading",
-		"/profile",
-		"/schedule",
-		"/schedule-event/*id",
-		"/schedule-event-info/*id",
-		"/profile-yaml",
-		"/addressable",
-		"/notification",
-		"/subscription",
-		"/transmission",
-		"/export",
-		"/log",
-		"/login",
-	}
-
-	SPAFile(r, paths, "./assets/index.html")
-	r.Static("/js", "./assets/js")
-	r.Static("/css", "./assets/css")
-	r.Static("/img", "./assets/img")
-	r.Static("/fonts", "./assets/fonts")
-
-	return r
+	web.addWebClient(base, assets)
+
+	return r, base
 }
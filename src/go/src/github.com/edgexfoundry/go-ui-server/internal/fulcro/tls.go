@@ -0,0 +1,200 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package fulcro
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	selfSignedCert     = "self-signed-cert.pem"
+	selfSignedKey      = "self-signed-key.pem"
+	selfSignedValidity = 365 * 24 * time.Hour
+)
+
+// TLSConfig describes how the server is reached over HTTPS.
+type TLSConfig struct {
+	// EnableTLS serves HTTPS instead of HTTP on the server port
+	EnableTLS bool
+	// CertFile and KeyFile hold the PEM encoded certificate chain and key.
+	// A self-signed certificate is used if they are not set.
+	CertFile string
+	KeyFile  string
+	// MinTLSVersion is the oldest protocol version accepted, "1.2" by default
+	MinTLSVersion string
+	// SelfSignedDir is where a generated certificate is kept across restarts
+	SelfSignedDir string
+	// SelfSignedHosts are the host names and addresses the generated
+	// certificate is valid for
+	SelfSignedHosts []string
+	// RedirectPort, if set, is a plain HTTP port redirecting to HTTPS
+	RedirectPort int
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func (c TLSConfig) minVersion() (uint16, error) {
+	if c.MinTLSVersion == "" {
+		return tls.VersionTLS12, nil
+	}
+	version, ok := tlsVersions[c.MinTLSVersion]
+	if !ok {
+		return 0, fmt.Errorf("unsupported minimum TLS version %s", c.MinTLSVersion)
+	}
+	return version, nil
+}
+
+// Validate checks the settings that can be checked without reading the
+// certificate files.
+func (c TLSConfig) Validate() error {
+	if _, err := c.minVersion(); err != nil {
+		return err
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("both CertFile and KeyFile must be set")
+	}
+	return nil
+}
+
+// certificateFiles returns the certificate and key to serve, generating a
+// self-signed pair when none is configured.
+func (c TLSConfig) certificateFiles() (string, string, error) {
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return "", "", fmt.Errorf("both CertFile and KeyFile must be set")
+		}
+		return c.CertFile, c.KeyFile, nil
+	}
+	dir := c.SelfSignedDir
+	if dir == "" {
+		dir = "."
+	}
+	certFile := filepath.Join(dir, selfSignedCert)
+	keyFile := filepath.Join(dir, selfSignedKey)
+	if pair, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		if cert, err := x509.ParseCertificate(pair.Certificate[0]); err == nil && time.Now().Add(24*time.Hour).Before(cert.NotAfter) {
+			return certFile, keyFile, nil
+		}
+	}
+	fmt.Fprintf(os.Stdout, "Generating self-signed certificate in %s\n", dir)
+	if err := generateSelfSigned(certFile, keyFile, c.SelfSignedHosts); err != nil {
+		return "", "", fmt.Errorf("could not create self-signed certificate: %v", err)
+	}
+	return certFile, keyFile, nil
+}
+
+func generateSelfSigned(certFile string, keyFile string, hosts []string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+	if len(hosts) == 0 {
+		hosts = []string{"localhost", "127.0.0.1"}
+	}
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"EdgeX UI"}, CommonName: hosts[0]},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(certFile), 0700); err != nil {
+		return err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+	if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		return err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return ioutil.WriteFile(certFile, certPEM, 0644)
+}
+
+// redirectToHTTPS answers plain HTTP requests with a redirect to the same
+// URL on the HTTPS port.
+func redirectToHTTPS(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
+
+// ListenAndServe serves handler on all interfaces at port, over HTTPS if
+// enabled in config.
+func ListenAndServe(handler http.Handler, port int, config TLSConfig) error {
+	addr := ":" + strconv.Itoa(port)
+	if !config.EnableTLS {
+		return http.ListenAndServe(addr, handler)
+	}
+	minVersion, err := config.minVersion()
+	if err != nil {
+		return err
+	}
+	certFile, keyFile, err := config.certificateFiles()
+	if err != nil {
+		return err
+	}
+	server := &http.Server{
+		Addr:      addr,
+		Handler:   handler,
+		TLSConfig: &tls.Config{MinVersion: minVersion},
+	}
+	if config.RedirectPort != 0 {
+		go func() {
+			redirectAddr := ":" + strconv.Itoa(config.RedirectPort)
+			if err := http.ListenAndServe(redirectAddr, redirectToHTTPS(port)); err != nil {
+				fmt.Fprintf(os.Stderr, "HTTP redirect listener failed: %v\n", err)
+			}
+		}()
+	}
+	return server.ListenAndServeTLS(certFile, keyFile)
+}
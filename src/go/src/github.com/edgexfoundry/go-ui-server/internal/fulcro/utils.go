@@ -6,7 +6,6 @@ package fulcro
 
 import (
 	"github.com/russolsen/transit"
-	"gopkg.in/resty.v1"
 )
 
 func keywordize(data interface{}) interface{} {
@@ -43,63 +42,13 @@ func Keywordize(data interface{}, err error) (interface{}, error) {
 	return result, err
 }
 
-func MakeKeyword(data interface{}, keys ...string) interface{} {
-	var result interface{}
-	switch v := data.(type) {
-	case []map[string]interface{}:
-		newArray := make([]map[string]interface{}, len(v))
-		for i, m := range v {
-			newArray[i] = MakeKeyword(m, keys...).(map[string]interface{})
-		}
-		result = newArray
-	case map[string]interface{}:
-		key := keys[0]
-		val, ok := v[key]
-		if ok {
-			if len(keys) == 1 {
-				v[key] = transit.Keyword(val.(string))
-			} else {
-				v[key] = MakeKeyword(val, keys[1:]...)
-			}
-		}
-		result = v
-	default:
-		result = v
-	}
-	return result
-}
-
-func Remove(data interface{}, keys ...string) interface{} {
-	var result interface{}
-	switch v := data.(type) {
-	case []map[string]interface{}:
-		for i, m := range v {
-			v[i] = Remove(m, keys...).(map[string]interface{})
-		}
-		result = v
-	case map[string]interface{}:
-		key := keys[0]
-		val, ok := v[key]
-		if ok {
-			if len(keys) == 1 {
-				delete(v, key)
-			} else {
-				v[key] = Remove(val, keys[1:]...)
-			}
-		}
-		result = v
-	default:
-		result = v
-	}
-	return result
-}
+// Masked replaces secrets sent to the client.
+const Masked = "********"
 
-func AddType(data interface{}, t string) interface{} {
-	result := data.([]map[string]interface{})
-	for i, _ := range result {
-		result[i]["type"] = transit.Keyword(t)
-	}
-	return result
+// IsMasked tells whether a secret sent back by the client is unchanged, as
+// either Masked or empty.
+func IsMasked(s string) bool {
+	return s == "" || s == Masked
 }
 
 func GetString(args map[interface{}]interface{}, id string) string {
@@ -123,6 +72,14 @@ func GetInt(args map[interface{}]interface{}, id string) int64 {
 	return args[transit.Keyword(id)].(int64)
 }
 
+// GetIntOr returns the integer argument id, or def if it is not given.
+func GetIntOr(args map[interface{}]interface{}, id string, def int64) int64 {
+	if val, ok := args[transit.Keyword(id)].(int64); ok {
+		return val
+	}
+	return def
+}
+
 func GetBool(args map[interface{}]interface{}, id string) bool {
 	return args[transit.Keyword(id)].(bool)
 }
@@ -135,22 +92,6 @@ func GetMap(args map[interface{}]interface{}, id string) map[interface{}]interfa
 	return args[transit.Keyword(id)].(map[interface{}]interface{})
 }
 
-func GetPrtsMap(args map[interface{}]interface{}, id string) map[string]interface{} {
-	prts := args[transit.Keyword(id)].(map[interface{}]interface{})
-	result := make(map[string]interface{})
-	for i, v := range prts {
-		inner_result := v.(map[interface{}]interface{})
-		child_node := make(map[string]string)
-		for j, w := range inner_result {
-			key := string(j.(transit.Keyword))
-			child_node[key] = w.(string)
-		}
-		pr_key := string(i.(transit.Keyword))
-		result[pr_key] = child_node
-	}
-	return result;
-}
-
 func GetStringSeq(args map[interface{}]interface{}, id string) []string {
 	seq := args[transit.Keyword(id)].([]interface{})
 	result := make([]string, len(seq))
@@ -160,36 +101,6 @@ func GetStringSeq(args map[interface{}]interface{}, id string) []string {
 	return result
 }
 
-func GetMapStringSeq(args map[interface{}]interface{}, id string) []map[string]interface{} {
-	seq := args[transit.Keyword(id)].([]interface{})
-	var result []map[string]interface{}
-	for _, v := range seq {
-		inner_result := v.(map[interface{}]interface{})
-		child_node := make(map[string]interface{})
-		for j, w := range inner_result {
-			key := string(j.(transit.Keyword))
-			switch w.(type) {
-			case bool:
-				child_node[key] = w
-			case string:
-				child_node[key] = w.(string)
-			case int:
-				child_node[key] = w.(int)
-			}
-		}
-		result = append(result, child_node)
-	}
-	return result
-}
-
-func MkTempIdResult(tempid transit.TaggedValue, resp *resty.Response) interface{} {
-	result := make(map[interface{}]interface{})
-	tempMap := transit.NewCMap()
-	tempMap = tempMap.Put(tempid, transit.Keyword(resp.String()), transit.Equals)
-	result[transit.Keyword("fulcro.client.primitives/tempids")] = tempMap
-	return result
-}
-
 func MkTempResult(tempid transit.TaggedValue, val interface{}) interface{} {
 	result := make(map[interface{}]interface{})
 	tempMap := transit.NewCMap()
@@ -0,0 +1,220 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package fulcro
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultRoutes are the routes of the web client answered with index.html
+// if none are configured.
+var DefaultRoutes = []string{
+	"/",
+	"/info/*id",
+	"/command/*id",
+	"/reading",
+	"/profile",
+	"/schedule",
+	"/schedule-event/*id",
+	"/schedule-event-info/*id",
+	"/profile-yaml",
+	"/addressable",
+	"/notification",
+	"/subscription",
+	"/transmission",
+	"/export",
+	"/log",
+	"/login",
+}
+
+// WebConfig sets where the web client and the API are served.
+type WebConfig struct {
+	// BasePath is the URL path everything is served under, such as
+	// "/edgex-ui/" behind a reverse proxy, "/" by default
+	BasePath string
+	// AssetDir, if set, holds index.html and the directories of the web
+	// client served instead of those embedded in the binary
+	AssetDir string
+	// Routes are the routes of the web client, below BasePath, answered
+	// with index.html, in gin syntax such as "/info/*id". DefaultRoutes if
+	// not set.
+	Routes []string
+}
+
+// headTag is where the base element is added to index.html
+var headTag = regexp.MustCompile(`(?i)<head[^>]*>`)
+
+// hashedName matches file names carrying a content hash, such as
+// main.3f2a9c1d.js, which never change and may be cached for good
+var hashedName = regexp.MustCompile(`[.-][0-9a-fA-F]{8,}\.[^/]+$`)
+
+// Base returns the base path, starting and ending with "/".
+func (c WebConfig) Base() string {
+	base := "/" + strings.Trim(c.BasePath, "/") + "/"
+	if base == "//" {
+		return "/"
+	}
+	return base
+}
+
+// files returns the files of the web client, those in AssetDir if set or
+// else embedded.
+func (c WebConfig) files(embedded fs.FS) (fs.FS, error) {
+	if c.AssetDir != "" {
+		return os.DirFS(c.AssetDir), nil
+	}
+	if embedded == nil {
+		return nil, fmt.Errorf("no web client embedded and no AssetDir set")
+	}
+	return embedded, nil
+}
+
+func (c WebConfig) routes() []string {
+	if len(c.Routes) == 0 {
+		return DefaultRoutes
+	}
+	return c.Routes
+}
+
+// Validate checks the base path and routes.
+func (c WebConfig) Validate() error {
+	if c.BasePath != "" && !strings.HasPrefix(c.BasePath, "/") {
+		return fmt.Errorf("BasePath %q must start with /", c.BasePath)
+	}
+	if strings.ContainsAny(c.BasePath, ":*?#") {
+		return fmt.Errorf("BasePath %q must be a plain path", c.BasePath)
+	}
+	for _, route := range c.Routes {
+		if !strings.HasPrefix(route, "/") {
+			return fmt.Errorf("route %q must start with /", route)
+		}
+	}
+	if c.AssetDir != "" {
+		if _, err := os.Stat(path.Join(c.AssetDir, "index.html")); err != nil {
+			return fmt.Errorf("AssetDir %s has no index.html", c.AssetDir)
+		}
+	}
+	return nil
+}
+
+// assetServer serves the files of the web client with an ETag, remembered
+// for each file until it changes.
+type assetServer struct {
+	files fs.FS
+	mutex sync.Mutex
+	etags map[string]assetTag
+}
+
+type assetTag struct {
+	modTime time.Time
+	size    int64
+	etag    string
+}
+
+// read returns the contents, modification time and ETag of the file name.
+func (a *assetServer) read(name string) ([]byte, time.Time, string, error) {
+	info, err := fs.Stat(a.files, name)
+	if err != nil {
+		return nil, time.Time{}, "", err
+	}
+	if info.IsDir() {
+		return nil, time.Time{}, "", fs.ErrNotExist
+	}
+	data, err := fs.ReadFile(a.files, name)
+	if err != nil {
+		return nil, time.Time{}, "", err
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	tag, ok := a.etags[name]
+	if !ok || !tag.modTime.Equal(info.ModTime()) || tag.size != info.Size() {
+		sum := sha256.Sum256(data)
+		tag = assetTag{modTime: info.ModTime(), size: info.Size(), etag: `"` + hex.EncodeToString(sum[:16]) + `"`}
+		a.etags[name] = tag
+	}
+	return data, info.ModTime(), tag.etag, nil
+}
+
+// serveFile serves the file name of the web client. Files with a hashed
+// name are cached for good, the others revalidated with their ETag.
+func (a *assetServer) serveFile(ctx *gin.Context, name string) {
+	data, modTime, etag, err := a.read(name)
+	if err != nil {
+		ctx.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+	if hashedName.MatchString(name) {
+		ctx.Header("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		ctx.Header("Cache-Control", "no-cache")
+	}
+	ctx.Header("ETag", etag)
+	http.ServeContent(ctx.Writer, ctx.Request, name, modTime, bytes.NewReader(data))
+}
+
+// indexHandler serves index.html with a base element set to the base path,
+// so that the page resolves its assets and the web client its routes and
+// API below it.
+func (c WebConfig) indexHandler(a *assetServer) gin.HandlerFunc {
+	base := `<base href="` + html.EscapeString(c.Base()) + `">`
+	return func(ctx *gin.Context) {
+		page, _, etag, err := a.read("index.html")
+		if err != nil {
+			ctx.String(http.StatusNotFound, "index.html not found")
+			return
+		}
+		if loc := headTag.FindIndex(page); loc != nil {
+			page = append(page[:loc[1]:loc[1]], append([]byte("\n        "+base), page[loc[1]:]...)...)
+		}
+		ctx.Header("Cache-Control", "no-cache")
+		// the page depends on the base path as well as on the file
+		ctx.Header("ETag", strings.TrimSuffix(etag, `"`)+"-"+hex.EncodeToString([]byte(c.Base()))+`"`)
+		http.ServeContent(ctx.Writer, ctx.Request, "index.html", time.Time{}, bytes.NewReader(page))
+	}
+}
+
+// addWebClient serves the routes of the web client with index.html and the
+// files in each directory of the web client, such as js and css. The files
+// are those in AssetDir if set, else those embedded.
+func (c WebConfig) addWebClient(group *gin.RouterGroup, embedded fs.FS) {
+	files, err := c.files(embedded)
+	if err != nil {
+		fmt.Printf("no web client: %v\n", err)
+		return
+	}
+	a := &assetServer{files: files, etags: make(map[string]assetTag)}
+	index := c.indexHandler(a)
+	for _, route := range c.routes() {
+		group.GET(route, index)
+	}
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		fmt.Printf("no web client: %v\n", err)
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := entry.Name()
+		group.GET("/"+dir+"/*filepath", func(ctx *gin.Context) {
+			a.serveFile(ctx, path.Join(dir, ctx.Param("filepath")))
+		})
+	}
+}
@@ -0,0 +1,441 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-ui-server/internal/auth"
+)
+
+const (
+	// pendingTimeout is how long a user has to log in at the provider
+	pendingTimeout = 10 * time.Minute
+	// clockSkew is the tolerance when checking token times
+	clockSkew = 2 * time.Minute
+	// keyRefreshInterval limits how often the key set is fetched again for
+	// an unknown key id
+	keyRefreshInterval = time.Minute
+)
+
+var ErrUnknownState = errors.New("Unknown or expired login request")
+
+// Config describes the identity provider and how its users map to roles.
+type Config struct {
+	// Enabled turns on login through the provider
+	Enabled bool
+	// Issuer is the provider URL, its configuration is read from
+	// Issuer/.well-known/openid-configuration
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback registered with the provider, ending in
+	// /auth/oidc/callback
+	RedirectURL string
+	// Scopes requested in addition to openid
+	Scopes []string
+	// UsernameClaim names the user, "preferred_username" if not set
+	UsernameClaim string
+	// GroupsClaim lists the groups of the user, "groups" if not set
+	GroupsClaim string
+	// Groups maps group names to the roles viewer, operator or admin. A user
+	// in several groups gets the highest role.
+	Groups map[string]string
+	// DefaultRole is given to users in none of the groups. They may not log
+	// in if it is not set.
+	DefaultRole string
+	// DisableLocalLogin allows logins through the provider only
+	DisableLocalLogin bool
+	// Timeout is the timeout in seconds of requests to the provider
+	Timeout int
+	// CAFile is a PEM bundle of the CAs trusted for the provider, the system
+	// roots if not set
+	CAFile string
+}
+
+// Identity is a user authenticated by the provider.
+type Identity struct {
+	Subject string
+	Name    string
+	Groups  []string
+	Role    auth.Role
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// pending is a login started at the provider but not completed yet.
+type pending struct {
+	nonce    string
+	verifier string
+	created  time.Time
+}
+
+// Provider runs the OpenID Connect authorization code flow, with PKCE,
+// against one identity provider.
+type Provider struct {
+	config Config
+	roles  map[string]auth.Role
+	role   auth.Role
+	client *http.Client
+
+	mutex       sync.Mutex
+	metadata    *discovery
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+	pending     map[string]pending
+}
+
+// New checks config and returns its provider. The provider itself is first
+// contacted when a user logs in.
+func New(config Config) (*Provider, error) {
+	var missing []string
+	if config.Issuer == "" {
+		missing = append(missing, "Issuer")
+	}
+	if config.ClientID == "" {
+		missing = append(missing, "ClientID")
+	}
+	if config.RedirectURL == "" {
+		missing = append(missing, "RedirectURL")
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("OIDC %s must be set", strings.Join(missing, ", "))
+	}
+	if config.UsernameClaim == "" {
+		config.UsernameClaim = "preferred_username"
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10
+	}
+	p := &Provider{
+		config:  config,
+		roles:   make(map[string]auth.Role, len(config.Groups)),
+		client:  &http.Client{Timeout: time.Duration(config.Timeout) * time.Second},
+		pending: make(map[string]pending),
+	}
+	if config.CAFile != "" {
+		pem, err := ioutil.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read OIDC CA bundle (%s): %v", config.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in OIDC CA bundle (%s)", config.CAFile)
+		}
+		p.client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		}
+	}
+	for group, name := range config.Groups {
+		role, err := auth.ParseRole(name)
+		if err != nil {
+			return nil, fmt.Errorf("OIDC group %s: %v", group, err)
+		}
+		p.roles[group] = role
+	}
+	if config.DefaultRole != "" {
+		role, err := auth.ParseRole(config.DefaultRole)
+		if err != nil {
+			return nil, fmt.Errorf("OIDC DefaultRole: %v", err)
+		}
+		p.role = role
+	}
+	return p, nil
+}
+
+// Config returns the configuration of the provider.
+func (p *Provider) Config() Config {
+	return p.config
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// discover reads the provider configuration once.
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mutex.Lock()
+	metadata := p.metadata
+	p.mutex.Unlock()
+	if metadata != nil {
+		return metadata, nil
+	}
+	metadata = &discovery{}
+	wellKnown := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, wellKnown, metadata); err != nil {
+		return nil, fmt.Errorf("could not read OIDC provider configuration: %v", err)
+	}
+	if metadata.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("OIDC provider issuer %s does not match %s", metadata.Issuer, p.config.Issuer)
+	}
+	p.mutex.Lock()
+	p.metadata = metadata
+	p.mutex.Unlock()
+	return metadata, nil
+}
+
+// AuthURL starts a login and returns the provider URL to send the user to
+// together with the state identifying the login.
+func (p *Provider) AuthURL(ctx context.Context) (string, string, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomString(16)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	now := time.Now()
+	p.mutex.Lock()
+	for s, login := range p.pending {
+		if now.Sub(login.created) > pendingTimeout {
+			delete(p.pending, s)
+		}
+	}
+	p.pending[state] = pending{nonce: nonce, verifier: verifier, created: now}
+	p.mutex.Unlock()
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(append([]string{"openid"}, p.config.Scopes...), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + params.Encode(), state, nil
+}
+
+// Exchange completes the login identified by state with the code returned by
+// the provider and returns the verified identity of the user.
+func (p *Provider) Exchange(ctx context.Context, state string, code string) (*Identity, error) {
+	p.mutex.Lock()
+	login, ok := p.pending[state]
+	delete(p.pending, state)
+	p.mutex.Unlock()
+	if !ok || time.Since(login.created) > pendingTimeout {
+		return nil, ErrUnknownState
+	}
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {login.verifier},
+	}
+	req, err := http.NewRequest(http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("OIDC token request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OIDC token request failed: %s %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil || tokens.IDToken == "" {
+		return nil, errors.New("OIDC token response has no ID token")
+	}
+	claims, err := p.verify(ctx, metadata, tokens.IDToken, login.nonce)
+	if err != nil {
+		return nil, err
+	}
+	return p.identity(claims)
+}
+
+// keyFor returns the provider key kid, fetching the key set if it is not
+// known yet.
+func (p *Provider) keyFor(ctx context.Context, metadata *discovery, kid string) (crypto.PublicKey, error) {
+	p.mutex.Lock()
+	key, ok := p.keys[kid]
+	fetched := p.keysFetched
+	p.mutex.Unlock()
+	if ok {
+		return key, nil
+	}
+	if time.Since(fetched) < keyRefreshInterval {
+		return nil, fmt.Errorf("unknown ID token key %s", kid)
+	}
+	var set keySet
+	if err := p.getJSON(ctx, metadata.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("could not read OIDC provider keys: %v", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if publicKey, err := k.publicKey(); err == nil {
+			keys[k.Kid] = publicKey
+		}
+	}
+	p.mutex.Lock()
+	p.keys = keys
+	p.keysFetched = time.Now()
+	p.mutex.Unlock()
+	if key, ok = keys[kid]; !ok {
+		return nil, fmt.Errorf("unknown ID token key %s", kid)
+	}
+	return key, nil
+}
+
+// verify checks the signature, issuer, audience, lifetime and nonce of an ID
+// token and returns its claims.
+func (p *Provider) verify(ctx context.Context, metadata *discovery, token string, nonce string) (map[string]interface{}, error) {
+	header, signed, signature, payload, err := parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+	key, err := p.keyFor(ctx, metadata, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, signed, signature); err != nil {
+		return nil, err
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.New("malformed ID token claims")
+	}
+	if iss, _ := claims["iss"].(string); iss != metadata.Issuer {
+		return nil, errors.New("ID token issuer does not match")
+	}
+	if !audienceContains(claims["aud"], p.config.ClientID) {
+		return nil, errors.New("ID token audience does not match")
+	}
+	now := time.Now()
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return nil, errors.New("ID token expired")
+	}
+	if iat, ok := claims["iat"].(float64); ok && time.Unix(int64(iat), 0).After(now.Add(clockSkew)) {
+		return nil, errors.New("ID token issued in the future")
+	}
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return nil, errors.New("ID token nonce does not match")
+	}
+	return claims, nil
+}
+
+func audienceContains(aud interface{}, clientID string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == clientID
+	case []interface{}:
+		for _, v := range a {
+			if s, ok := v.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// identity maps the claims of a verified ID token to a user and role.
+func (p *Provider) identity(claims map[string]interface{}) (*Identity, error) {
+	identity := &Identity{Role: p.role}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Name, _ = claims[p.config.UsernameClaim].(string)
+	if identity.Name == "" {
+		identity.Name = identity.Subject
+	}
+	if identity.Name == "" {
+		return nil, errors.New("ID token names no user")
+	}
+	switch groups := claims[p.config.GroupsClaim].(type) {
+	case string:
+		identity.Groups = []string{groups}
+	case []interface{}:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				identity.Groups = append(identity.Groups, s)
+			}
+		}
+	}
+	for _, group := range identity.Groups {
+		if role, ok := p.roles[group]; ok && role > identity.Role {
+			identity.Role = role
+		}
+	}
+	if identity.Role == auth.RoleNone {
+		return nil, auth.ErrForbidden
+	}
+	return identity, nil
+}
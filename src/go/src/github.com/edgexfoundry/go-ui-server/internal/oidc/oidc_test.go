@@ -0,0 +1,266 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-ui-server/internal/auth"
+	"github.com/edgexfoundry/go-ui-server/internal/oidc/oidctest"
+)
+
+const testRedirectURL = "https://ui.example.com/auth/oidc/callback"
+
+func testConfig(tp *oidctest.Provider) Config {
+	return Config{
+		Enabled:      true,
+		Issuer:       tp.URL,
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  testRedirectURL,
+		Groups:       map[string]string{"ops": "operator", "admins": "admin"},
+		DefaultRole:  "viewer",
+	}
+}
+
+// login runs the browser's part of a login with provider at tp and returns
+// the state and code sent back to the UI.
+func login(t *testing.T, tp *oidctest.Provider, provider *Provider) (string, string) {
+	authURL, state, err := provider.AuthURL(context.Background())
+	if err != nil {
+		t.Fatalf("AuthURL: %v", err)
+	}
+	back, err := tp.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.Query().Get("state") != state {
+		t.Fatalf("provider returned state %q, want %q", back.Query().Get("state"), state)
+	}
+	return state, back.Query().Get("code")
+}
+
+func TestDiscovery(t *testing.T) {
+	tp := oidctest.NewProvider(t)
+	provider, err := New(testConfig(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, state, err := provider.AuthURL(context.Background())
+	if err != nil {
+		t.Fatalf("AuthURL: %v", err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != tp.URL+"/authorize" {
+		t.Errorf("authorization endpoint %s, want %s", got, tp.URL+"/authorize")
+	}
+	query := u.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             oidctest.ClientID,
+		"redirect_uri":          testRedirectURL,
+		"scope":                 "openid",
+		"state":                 state,
+		"code_challenge_method": "S256",
+	}
+	for name, value := range want {
+		if query.Get(name) != value {
+			t.Errorf("%s = %q, want %q", name, query.Get(name), value)
+		}
+	}
+	if query.Get("nonce") == "" || query.Get("code_challenge") == "" {
+		t.Errorf("nonce or code_challenge missing in %s", authURL)
+	}
+
+	tp.SetIssuer("https://other.example.com")
+	provider, _ = New(testConfig(tp))
+	if _, _, err := provider.AuthURL(context.Background()); err == nil {
+		t.Error("AuthURL accepted a provider announcing another issuer")
+	}
+
+	provider, _ = New(Config{Issuer: tp.URL + "/missing", ClientID: oidctest.ClientID, RedirectURL: testRedirectURL})
+	if _, _, err := provider.AuthURL(context.Background()); err == nil {
+		t.Error("AuthURL succeeded without a provider configuration")
+	}
+}
+
+func TestLogin(t *testing.T) {
+	for _, alg := range []string{"RS256", "ES256"} {
+		t.Run(alg, func(t *testing.T) {
+			tp := oidctest.NewProvider(t)
+			tp.Set(alg, nil)
+			provider, err := New(testConfig(tp))
+			if err != nil {
+				t.Fatal(err)
+			}
+			state, code := login(t, tp, provider)
+			identity, err := provider.Exchange(context.Background(), state, code)
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if identity.Name != "alice" || identity.Subject != "u-42" || identity.Role != auth.RoleOperator {
+				t.Errorf("identity %+v, want alice (u-42) as operator", identity)
+			}
+		})
+	}
+}
+
+func TestState(t *testing.T) {
+	tp := oidctest.NewProvider(t)
+	provider, err := New(testConfig(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := login(t, tp, provider)
+	if _, err := provider.Exchange(context.Background(), "unknown", code); err != ErrUnknownState {
+		t.Errorf("Exchange with an unknown state: %v, want %v", err, ErrUnknownState)
+	}
+	if _, err := provider.Exchange(context.Background(), state, code); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if _, err := provider.Exchange(context.Background(), state, code); err != ErrUnknownState {
+		t.Errorf("Exchange of a completed login: %v, want %v", err, ErrUnknownState)
+	}
+
+	// a state started by another provider instance has no verifier here
+	other, _ := New(testConfig(tp))
+	state, code = login(t, tp, other)
+	if _, err := provider.Exchange(context.Background(), state, code); err != ErrUnknownState {
+		t.Errorf("Exchange of a login started elsewhere: %v, want %v", err, ErrUnknownState)
+	}
+}
+
+func TestPKCE(t *testing.T) {
+	tp := oidctest.NewProvider(t)
+	provider, err := New(testConfig(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, code := login(t, tp, provider)
+	// the code of one login does not complete another, whose verifier does
+	// not match the challenge the code was issued for
+	state, _ := login(t, tp, provider)
+	if _, err := provider.Exchange(context.Background(), state, code); err == nil {
+		t.Error("Exchange accepted a code issued for another login")
+	}
+}
+
+func TestSignature(t *testing.T) {
+	tp := oidctest.NewProvider(t)
+	tp.Set("forged", nil)
+	provider, err := New(testConfig(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := login(t, tp, provider)
+	if _, err := provider.Exchange(context.Background(), state, code); err == nil {
+		t.Error("Exchange accepted an ID token signed with an unknown key")
+	}
+
+	// an ES256 header on a token for the RSA key
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": "rsa"})
+	parts := strings.Split(tp.Sign("RS256", map[string]interface{}{"iss": tp.URL}), ".")
+	token := oidctest.Encode(header) + "." + parts[1] + "." + parts[2]
+	metadata, err := provider.discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.verify(context.Background(), metadata, token, ""); err == nil {
+		t.Error("verify accepted a token whose algorithm does not match its key")
+	}
+	if _, err := provider.verify(context.Background(), metadata, "not.a-token", ""); err == nil {
+		t.Error("verify accepted a malformed token")
+	}
+}
+
+func TestClaims(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(claims map[string]interface{})
+	}{
+		{"issuer", func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" }},
+		{"audience", func(c map[string]interface{}) { c["aud"] = "another-client" }},
+		{"audience list", func(c map[string]interface{}) { c["aud"] = []string{"a", "b"} }},
+		{"nonce", func(c map[string]interface{}) { c["nonce"] = "replayed" }},
+		{"no nonce", func(c map[string]interface{}) { delete(c, "nonce") }},
+		{"expired", func(c map[string]interface{}) { c["exp"] = time.Now().Add(-clockSkew - time.Minute).Unix() }},
+		{"no expiry", func(c map[string]interface{}) { delete(c, "exp") }},
+		{"issued in the future", func(c map[string]interface{}) { c["iat"] = time.Now().Add(time.Hour).Unix() }},
+	}
+	tp := oidctest.NewProvider(t)
+	provider, err := New(testConfig(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tp.Set("RS256", test.change)
+			state, code := login(t, tp, provider)
+			if identity, err := provider.Exchange(context.Background(), state, code); err == nil {
+				t.Errorf("Exchange accepted the ID token, identity %+v", identity)
+			}
+		})
+	}
+
+	t.Run("audience in list", func(t *testing.T) {
+		tp.Set("ES256", func(c map[string]interface{}) { c["aud"] = []string{"other", oidctest.ClientID} })
+		state, code := login(t, tp, provider)
+		if _, err := provider.Exchange(context.Background(), state, code); err != nil {
+			t.Errorf("Exchange: %v", err)
+		}
+	})
+}
+
+func TestGroupRoles(t *testing.T) {
+	tests := []struct {
+		name        string
+		defaultRole string
+		claims      map[string]interface{}
+		user        string
+		role        auth.Role
+		err         error
+	}{
+		{"highest group", "", map[string]interface{}{"preferred_username": "bob", "groups": []interface{}{"ops", "admins", "other"}}, "bob", auth.RoleAdmin, nil},
+		{"single group", "", map[string]interface{}{"preferred_username": "bob", "groups": "ops"}, "bob", auth.RoleOperator, nil},
+		{"default role", "viewer", map[string]interface{}{"preferred_username": "bob", "groups": []interface{}{"other"}}, "bob", auth.RoleViewer, nil},
+		{"group above default", "viewer", map[string]interface{}{"preferred_username": "bob", "groups": []interface{}{"admins"}}, "bob", auth.RoleAdmin, nil},
+		{"no group", "", map[string]interface{}{"preferred_username": "bob"}, "", auth.RoleNone, auth.ErrForbidden},
+		{"subject", "viewer", map[string]interface{}{"sub": "u-7"}, "u-7", auth.RoleViewer, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider, err := New(Config{
+				Issuer:      "https://idp.example.com",
+				ClientID:    oidctest.ClientID,
+				RedirectURL: testRedirectURL,
+				Groups:      map[string]string{"ops": "operator", "admins": "admin"},
+				DefaultRole: test.defaultRole,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			identity, err := provider.identity(test.claims)
+			if err != test.err {
+				t.Fatalf("identity: %v, want %v", err, test.err)
+			}
+			if err == nil && (identity.Name != test.user || identity.Role != test.role) {
+				t.Errorf("identity %s as %s, want %s as %s", identity.Name, identity.Role, test.user, test.role)
+			}
+		})
+	}
+
+	if _, err := New(Config{Issuer: "https://idp.example.com", ClientID: oidctest.ClientID, RedirectURL: testRedirectURL,
+		Groups: map[string]string{"ops": "superuser"}}); err == nil {
+		t.Error("New accepted a group mapped to an unknown role")
+	}
+}
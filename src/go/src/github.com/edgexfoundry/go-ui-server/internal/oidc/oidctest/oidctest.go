@@ -0,0 +1,229 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package oidctest provides a stand-in OpenID Connect provider for tests.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The client registered with the provider
+const (
+	ClientID     = "edgex-ui"
+	ClientSecret = "s3cret&"
+)
+
+// Provider is a stand-in identity provider serving discovery, the
+// authorization and token endpoints and its key set. It logs every user in
+// at once as alice (subject u-42) in the group ops.
+type Provider struct {
+	*httptest.Server
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+
+	mutex sync.Mutex
+	// issuer is announced by discovery, the server URL if empty
+	issuer string
+	// alg signs the ID tokens, RS256 or ES256
+	alg string
+	// claims changes the claims of the ID tokens before they are signed
+	claims func(claims map[string]interface{})
+	// logins are the authorization requests by code
+	logins map[string]url.Values
+}
+
+// NewProvider starts a provider, closed when the test ends.
+func NewProvider(t testing.TB) *Provider {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Provider{rsaKey: rsaKey, ecKey: ecKey, alg: "RS256", logins: make(map[string]url.Values)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/keys", p.keys)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// SetIssuer makes discovery announce issuer instead of the provider's URL.
+func (p *Provider) SetIssuer(issuer string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.issuer = issuer
+}
+
+// Set signs the ID tokens issued from now on with alg, RS256, ES256 or
+// "forged" for a key the provider does not publish, after changing their
+// claims with claims if it is not nil.
+func (p *Provider) Set(alg string, claims func(map[string]interface{})) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.alg = alg
+	p.claims = claims
+}
+
+// Authorize runs the browser's part of a login: it follows the
+// authorization URL and returns the callback URL the provider sends the
+// browser back to.
+func (p *Provider) Authorize(authURL string) (*url.URL, error) {
+	browser := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := browser.Get(authURL)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		return nil, fmt.Errorf("authorization request: %s", resp.Status)
+	}
+	return url.Parse(resp.Header.Get("Location"))
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	p.mutex.Lock()
+	issuer := p.issuer
+	p.mutex.Unlock()
+	if issuer == "" {
+		issuer = p.URL
+	}
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 issuer,
+		"authorization_endpoint": p.URL + "/authorize",
+		"token_endpoint":         p.URL + "/token",
+		"jwks_uri":               p.URL + "/keys",
+	})
+}
+
+// authorize logs the user in at once and sends the browser back with a code.
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	code := query.Get("state") + "-code"
+	p.mutex.Lock()
+	p.logins[code] = query
+	p.mutex.Unlock()
+	back := query.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	http.Redirect(w, r, back, http.StatusFound)
+}
+
+// token checks the client, the code and its PKCE verifier and returns a
+// signed ID token for the login.
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, secret, _ := r.BasicAuth()
+	if id != url.QueryEscape(ClientID) || secret != url.QueryEscape(ClientSecret) {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+	p.mutex.Lock()
+	login, ok := p.logins[r.Form.Get("code")]
+	delete(p.logins, r.Form.Get("code"))
+	p.mutex.Unlock()
+	challenge := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if !ok || r.Form.Get("grant_type") != "authorization_code" ||
+		r.Form.Get("redirect_uri") != login.Get("redirect_uri") ||
+		login.Get("code_challenge_method") != "S256" ||
+		login.Get("code_challenge") != Encode(challenge[:]) {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":                p.URL,
+		"sub":                "u-42",
+		"aud":                ClientID,
+		"exp":                now.Add(time.Hour).Unix(),
+		"iat":                now.Unix(),
+		"nonce":              login.Get("nonce"),
+		"preferred_username": "alice",
+		"groups":             []string{"ops"},
+	}
+	p.mutex.Lock()
+	change, alg := p.claims, p.alg
+	p.mutex.Unlock()
+	if change != nil {
+		change(claims)
+	}
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"id_token":     p.Sign(alg, claims),
+	})
+}
+
+func (p *Provider) keys(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kid": "rsa", "kty": "RSA", "use": "sig", "alg": "RS256",
+				"n": Encode(p.rsaKey.N.Bytes()),
+				"e": Encode(big.NewInt(int64(p.rsaKey.E)).Bytes()),
+			},
+			{
+				"kid": "ec", "kty": "EC", "use": "sig", "alg": "ES256", "crv": "P-256",
+				"x": Encode(p.ecKey.X.FillBytes(make([]byte, 32))),
+				"y": Encode(p.ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+			{"kid": "enc", "kty": "RSA", "use": "enc", "n": Encode(p.rsaKey.N.Bytes()), "e": "AQAB"},
+		},
+	})
+}
+
+// Encode returns b in unpadded base64url, as in a JWT.
+func Encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Sign returns claims as a compact JWS signed with the key for alg, or with
+// a key the provider does not publish for the "forged" alg.
+func (p *Provider) Sign(alg string, claims map[string]interface{}) string {
+	kid := map[string]string{"RS256": "rsa", "ES256": "ec", "forged": "rsa"}[alg]
+	header := map[string]string{"alg": alg, "kid": kid}
+	if alg == "forged" {
+		header["alg"] = "RS256"
+	}
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	signed := Encode(h) + "." + Encode(c)
+	digest := sha256.Sum256([]byte(signed))
+	var signature []byte
+	switch alg {
+	case "RS256":
+		signature, _ = rsa.SignPKCS1v15(rand.Reader, p.rsaKey, crypto.SHA256, digest[:])
+	case "ES256":
+		r, s, _ := ecdsa.Sign(rand.Reader, p.ecKey, digest[:])
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case "forged":
+		other, _ := rsa.GenerateKey(rand.Reader, 2048)
+		signature, _ = rsa.SignPKCS1v15(rand.Reader, other, crypto.SHA256, digest[:])
+	}
+	return signed + "." + Encode(signature)
+}
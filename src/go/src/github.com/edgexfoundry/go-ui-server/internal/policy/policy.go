@@ -0,0 +1,278 @@
+// Copyright (C) 2018 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Decision tells whether a command may be issued.
+type Decision string
+
+const (
+	Allow Decision = "allow"
+	// Confirm allows a command once the user confirmed it a second time
+	Confirm Decision = "confirm"
+	Deny    Decision = "deny"
+)
+
+// strictness orders decisions, the strictest one of all matching rules wins
+var strictness = map[Decision]int{Allow: 0, Confirm: 1, Deny: 2}
+
+// Selector picks devices and commands. Each field is a glob pattern as
+// understood by path.Match, an empty one matches everything. Label matches
+// if any label of the device matches.
+type Selector struct {
+	Device  string
+	Label   string
+	Profile string
+	Command string
+}
+
+// Rule gives the decision for the commands it selects.
+type Rule struct {
+	Selector
+	Decision Decision
+}
+
+// RateLimit allows at most Max commands to be issued to each selected device
+// within Window seconds.
+type RateLimit struct {
+	Selector
+	Max    int
+	Window int
+}
+
+// Config lists the rules and rate limits of the command policy. Commands no
+// rule selects get the Default decision, Allow if it is not set.
+type Config struct {
+	Default    Decision
+	Rules      []Rule
+	RateLimits []RateLimit
+}
+
+// Target is a command of a device to be checked against the policy.
+type Target struct {
+	Device  string
+	Labels  []string
+	Profile string
+	Command string
+}
+
+// RateLimitError is returned while a device must not be sent more commands.
+type RateLimitError struct {
+	Device     string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("Too many commands for device %s, try again in %d seconds",
+		e.Device, int(e.RetryAfter.Seconds()+0.5))
+}
+
+// Policy decides which commands may be issued and enforces the rate limits.
+type Policy struct {
+	mutex  sync.Mutex
+	config Config
+	// issued holds the commands issued, per rate limit and device
+	issued map[string][]issue
+	// generation counts the configurations, so that tickets of an earlier
+	// one are not released against the current one
+	generation uint64
+	// lastID is the id of the last command counted
+	lastID uint64
+}
+
+// issue is a command counted against a rate limit.
+type issue struct {
+	at time.Time
+	id uint64
+}
+
+// Ticket is a command counted by Take, to be given back with Release if it
+// was never sent.
+type Ticket struct {
+	policy     *Policy
+	generation uint64
+	id         uint64
+	keys       []string
+}
+
+// Commands is the policy used by the server, allowing every command until
+// configured.
+var Commands = &Policy{issued: make(map[string][]issue)}
+
+func validDecision(d Decision) bool {
+	_, ok := strictness[d]
+	return ok
+}
+
+func (s Selector) validate() error {
+	for _, pattern := range []string{s.Device, s.Label, s.Profile, s.Command} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+func match(pattern string, name string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// Matches reports whether s selects t.
+func (s Selector) Matches(t Target) bool {
+	if !match(s.Device, t.Device) || !match(s.Profile, t.Profile) || !match(s.Command, t.Command) {
+		return false
+	}
+	if s.Label == "" {
+		return true
+	}
+	for _, label := range t.Labels {
+		if match(s.Label, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// New returns the policy of config, after checking its decisions, patterns
+// and limits.
+func New(config Config) (*Policy, error) {
+	config.Default = Decision(strings.ToLower(string(config.Default)))
+	if config.Default == "" {
+		config.Default = Allow
+	}
+	if !validDecision(config.Default) {
+		return nil, fmt.Errorf("invalid default command decision %q", config.Default)
+	}
+	for i := range config.Rules {
+		rule := &config.Rules[i]
+		rule.Decision = Decision(strings.ToLower(string(rule.Decision)))
+		if !validDecision(rule.Decision) {
+			return nil, fmt.Errorf("command rule %d: invalid decision %q", i+1, rule.Decision)
+		}
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("command rule %d: %v", i+1, err)
+		}
+	}
+	for i, limit := range config.RateLimits {
+		if limit.Max <= 0 || limit.Window <= 0 {
+			return nil, fmt.Errorf("command rate limit %d: Max and Window must be positive", i+1)
+		}
+		if err := limit.validate(); err != nil {
+			return nil, fmt.Errorf("command rate limit %d: %v", i+1, err)
+		}
+	}
+	return &Policy{config: config, issued: make(map[string][]issue)}, nil
+}
+
+// Configure replaces the rules and rate limits of p by those of config,
+// after checking them as New does. Commands counted so far are forgotten.
+func (p *Policy) Configure(config Config) error {
+	n, err := New(config)
+	if err != nil {
+		return err
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.config = n.config
+	p.issued = n.issued
+	p.generation++
+	return nil
+}
+
+// Enabled reports whether any rule or rate limit is configured, else every
+// command is allowed.
+func (p *Policy) Enabled() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return (p.config.Default != "" && p.config.Default != Allow) ||
+		len(p.config.Rules) > 0 || len(p.config.RateLimits) > 0
+}
+
+// Decide returns the strictest decision of the rules selecting t, or the
+// default decision if there is none.
+func (p *Policy) Decide(t Target) Decision {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	decision := Decision("")
+	for _, rule := range p.config.Rules {
+		if rule.Matches(t) && (decision == "" || strictness[rule.Decision] > strictness[decision]) {
+			decision = rule.Decision
+		}
+	}
+	if decision == "" {
+		decision = p.config.Default
+	}
+	if decision == "" {
+		decision = Allow
+	}
+	return decision
+}
+
+// Take counts a command issued to the device of t against the rate limits
+// selecting it, or returns a RateLimitError if one of them is exhausted.
+func (p *Policy) Take(t Target) (Ticket, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	now := time.Now()
+	var keys []string
+	var wait time.Duration
+	for i, limit := range p.config.RateLimits {
+		if !limit.Matches(t) {
+			continue
+		}
+		key := fmt.Sprintf("%d/%s", i, t.Device)
+		window := time.Duration(limit.Window) * time.Second
+		issued := p.issued[key]
+		for len(issued) > 0 && now.Sub(issued[0].at) >= window {
+			issued = issued[1:]
+		}
+		p.issued[key] = issued
+		if len(issued) >= limit.Max {
+			if w := issued[len(issued)-limit.Max].at.Add(window).Sub(now); w > wait {
+				wait = w
+			}
+		}
+		keys = append(keys, key)
+	}
+	if wait > 0 {
+		return Ticket{}, &RateLimitError{Device: t.Device, RetryAfter: wait}
+	}
+	p.lastID++
+	for _, key := range keys {
+		p.issued[key] = append(p.issued[key], issue{at: now, id: p.lastID})
+	}
+	return Ticket{policy: p, generation: p.generation, id: p.lastID, keys: keys}, nil
+}
+
+// Release gives back the command of ticket, such as when it could not be
+// sent after all. Tickets taken before the policy was configured again are
+// ignored, as their commands are already forgotten.
+func (p *Policy) Release(ticket Ticket) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if ticket.policy != p || ticket.generation != p.generation {
+		return
+	}
+	for _, key := range ticket.keys {
+		issued := p.issued[key]
+		for i := range issued {
+			if issued[i].id == ticket.id {
+				p.issued[key] = append(issued[:i:i], issued[i+1:]...)
+				break
+			}
+		}
+	}
+}
@@ -5,68 +5,141 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"github.com/edgexfoundry/go-ui-server/internal/assets"
+	"github.com/edgexfoundry/go-ui-server/internal/audit"
+	"github.com/edgexfoundry/go-ui-server/internal/auth"
 	"github.com/edgexfoundry/go-ui-server/internal/edgex"
 	"github.com/edgexfoundry/go-ui-server/internal/fulcro"
-	"strconv"
+	"os"
 )
 
 func main() {
-	config, err := edgex.LoadConfig("")
-	edgex.InitEndpoints(config)
+	confDir := flag.String("confdir", "", "directory of the configuration file (default ./res)")
+	confFile := flag.String("file", "", "name of the configuration file (default configuration.toml)")
+	validateOnly := flag.Bool("validate-config", false, "check the configuration and exit, non-zero if it is invalid")
+	flag.Parse()
 
+	// Settings of the configuration file are overridden by EDGEX_UI_*
+	// environment variables, and endpoints by those saved from the UI
+	config, unknown, err := edgex.LoadEffectiveConfig(*confDir, *confFile)
+	for _, name := range unknown {
+		fmt.Fprintf(os.Stderr, "ignoring unknown setting %s\n", name)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *validateOnly {
+		fmt.Fprintln(os.Stdout, "Configuration is valid")
+		return
+	}
+	err = edgex.LoadEndpointState(config)
 	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Fprintln(os.Stdout, "Effective configuration:")
+	edgex.DumpConfig(os.Stdout, config)
+	err = edgex.ApplyConfig(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		return
 	}
 
+	if config.Login.SecurityLog != "" {
+		auth.Security, err = auth.OpenSecurityLog(config.Login.SecurityLog)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+	}
+	edgex.InitCookies(config)
+	err = edgex.InitOIDC(config.OIDC)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if config.Audit.File != "" {
+		audit.Trail, err = audit.Open(config.Audit.File)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+	}
+
 	server := fulcro.NewServer()
-	server.AddQueryFunc("q/login", edgex.Login)
-	server.AddQueryFunc("q/change-pw", edgex.ChangePassword)
-	server.AddQueryFunc("q/edgex-devices", edgex.Devices)
-	server.AddQueryFunc("q/edgex-device-services", edgex.DeviceServices)
-	server.AddQueryFunc("q/edgex-schedule-events", edgex.ScheduleEvents)
-	server.AddQueryFunc("q/edgex-addressables", edgex.Addressables)
-	server.AddQueryFunc("q/edgex-profiles", edgex.Profiles)
-	server.AddQueryFunc("q/edgex-profile-yaml", edgex.ProfileYaml)
-	server.AddQueryFunc("q/edgex-commands", edgex.Commands)
-	server.AddQueryFunc("q/edgex-readings", edgex.DeviceReadings)
-	server.AddQueryFunc("q/edgex-value-descriptors", edgex.ValueDescriptors)
-	server.AddQueryFunc("show-schedules", edgex.ShowSchedules)
-	server.AddQueryFunc("show-exports", edgex.ShowExports)
-	server.AddQueryFunc("show-notifications", edgex.ShowNotifications)
-	server.AddQueryFunc("show-subscriptions", edgex.ShowSubscriptions)
-	server.AddQueryFunc("show-transmissions", edgex.ShowTransmissions)
-	server.AddQueryFunc("show-profiles", edgex.ShowProfiles)
-	server.AddQueryFunc("show-devices", edgex.ShowDevices)
-	server.AddQueryFunc("show-addressables", edgex.ShowAddressables)
-	server.AddQueryFunc("show-logs", edgex.ShowLogs)
-	server.AddQueryFunc("show-commands", edgex.ShowCommands)
-	server.AddQueryFunc("reading-page", edgex.ReadingPage)
-	server.AddQueryFunc("endpoint", edgex.Endpoints)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/update-lock-mode", edgex.UpdateLockMode)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/save-endpoints", edgex.SaveEndpoints)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/upload-profile", edgex.UploadProfile)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/delete-profile", edgex.DeleteProfile)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/add-device", edgex.AddDevice)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/delete-device", edgex.DeleteDevice)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/add-addressable", edgex.AddAddressable)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/edit-addressable", edgex.EditAddressable)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/delete-addressable", edgex.DeleteAddressable)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/add-schedule", edgex.AddSchedule)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/delete-schedule", edgex.DeleteSchedule)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/add-schedule-event", edgex.AddScheduleEvent)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/delete-schedule-event", edgex.DeleteScheduleEvent)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/issue-set-command", edgex.IssueSetCommand)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/add-notification", edgex.AddNotification)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/delete-notification", edgex.DeleteNotification)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/add-subscription", edgex.AddSubscription)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/edit-subscription", edgex.EditSubscription)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/delete-subscription", edgex.DeleteSubscription)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/add-export", edgex.AddExport)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/edit-export", edgex.EditExport)
-	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/delete-export", edgex.DeleteExport)
-	router := server.SetupRouter()
-	edgex.AddUpload(router)
+	server.AddQueryFunc("q/login", auth.RoleNone, edgex.Login)
+	server.AddQueryFunc("q/change-pw", auth.RoleNone, edgex.ChangePassword)
+	server.AddQueryFunc("q/login-methods", auth.RoleNone, edgex.LoginMethods)
+	server.AddQueryFunc("q/sessions", auth.RoleViewer, edgex.Sessions)
+	server.AddQueryFunc("q/edgex-devices", auth.RoleViewer, edgex.Devices)
+	server.AddQueryFunc("q/edgex-device-services", auth.RoleViewer, edgex.DeviceServices)
+	server.AddQueryFunc("q/edgex-schedule-events", auth.RoleViewer, edgex.ScheduleEvents)
+	server.AddQueryFunc("q/edgex-addressables", auth.RoleViewer, edgex.Addressables)
+	server.AddQueryFunc("q/edgex-profiles", auth.RoleViewer, edgex.Profiles)
+	server.AddQueryFunc("q/edgex-profile-yaml", auth.RoleViewer, edgex.ProfileYaml)
+	server.AddQueryFunc("q/edgex-commands", auth.RoleViewer, edgex.Commands)
+	server.AddQueryFunc("q/edgex-readings", auth.RoleViewer, edgex.DeviceReadings)
+	server.AddQueryFunc("q/edgex-value-descriptors", auth.RoleViewer, edgex.ValueDescriptors)
+	server.AddQueryFunc("show-schedules", auth.RoleViewer, edgex.ShowSchedules)
+	server.AddQueryFunc("show-exports", auth.RoleViewer, edgex.ShowExports)
+	server.AddQueryFunc("show-notifications", auth.RoleViewer, edgex.ShowNotifications)
+	server.AddQueryFunc("show-subscriptions", auth.RoleViewer, edgex.ShowSubscriptions)
+	server.AddQueryFunc("show-transmissions", auth.RoleViewer, edgex.ShowTransmissions)
+	server.AddQueryFunc("show-profiles", auth.RoleViewer, edgex.ShowProfiles)
+	server.AddQueryFunc("show-devices", auth.RoleViewer, edgex.ShowDevices)
+	server.AddQueryFunc("show-addressables", auth.RoleViewer, edgex.ShowAddressables)
+	server.AddQueryFunc("show-logs", auth.RoleViewer, edgex.ShowLogs)
+	server.AddQueryFunc("show-commands", auth.RoleViewer, edgex.ShowCommands)
+	server.AddQueryFunc("reading-page", auth.RoleViewer, edgex.ReadingPage)
+	server.AddQueryFunc("endpoint", auth.RoleViewer, edgex.Endpoints)
+	server.AddQueryFunc("q/service-health", auth.RoleViewer, edgex.ServiceHealth)
+	server.AddQueryFunc("q/users", auth.RoleAdmin, edgex.Users)
+	server.AddQueryFunc("q/login-lockouts", auth.RoleAdmin, edgex.LoginLockouts)
+	server.AddQueryFunc("show-audit", auth.RoleAdmin, edgex.ShowAudit)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/logout", auth.RoleNone, edgex.Logout)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/revoke-session", auth.RoleViewer, edgex.RevokeSession)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/update-lock-mode", auth.RoleOperator, edgex.UpdateLockMode)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/save-endpoints", auth.RoleAdmin, edgex.SaveEndpoints)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/reset-endpoints", auth.RoleAdmin, edgex.ResetEndpoints)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/upload-profile", auth.RoleOperator, edgex.UploadProfile)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/delete-profile", auth.RoleOperator, edgex.DeleteProfile)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/add-device", auth.RoleOperator, edgex.AddDevice)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/delete-device", auth.RoleOperator, edgex.DeleteDevice)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/add-addressable", auth.RoleOperator, edgex.AddAddressable)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/edit-addressable", auth.RoleOperator, edgex.EditAddressable)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/delete-addressable", auth.RoleOperator, edgex.DeleteAddressable)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/add-schedule", auth.RoleOperator, edgex.AddSchedule)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/delete-schedule", auth.RoleOperator, edgex.DeleteSchedule)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/add-schedule-event", auth.RoleOperator, edgex.AddScheduleEvent)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/delete-schedule-event", auth.RoleOperator, edgex.DeleteScheduleEvent)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/issue-set-command", auth.RoleOperator, edgex.IssueSetCommand)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/add-notification", auth.RoleOperator, edgex.AddNotification)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/delete-notification", auth.RoleOperator, edgex.DeleteNotification)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/add-subscription", auth.RoleOperator, edgex.AddSubscription)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/edit-subscription", auth.RoleOperator, edgex.EditSubscription)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/delete-subscription", auth.RoleOperator, edgex.DeleteSubscription)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/add-export", auth.RoleOperator, edgex.AddExport)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/edit-export", auth.RoleOperator, edgex.EditExport)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/delete-export", auth.RoleOperator, edgex.DeleteExport)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/create-user", auth.RoleAdmin, edgex.CreateUser)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/disable-user", auth.RoleAdmin, edgex.DisableUser)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/reset-user-password", auth.RoleAdmin, edgex.ResetUserPassword)
+	server.AddMutationFunc("org.edgexfoundry.ui.manager.api.mutations/clear-login-lockout", auth.RoleAdmin, edgex.ClearLoginLockout)
+	router, base := server.SetupRouter(config.Server.SecurityConfig, config.Server.WebConfig, assets.FS())
+	edgex.AddUpload(base)
+	edgex.AddOIDC(base)
+
+	// The clients, policies and login settings follow changes of the
+	// configuration file, the other settings need a restart
+	go edgex.NewReloader(*confDir, *confFile, config).Watch()
 
 	// Listen on all interfaces at specified port
-	router.Run(":" + strconv.Itoa(config.Server.Port))
+	err = fulcro.ListenAndServe(router, config.Server.Port, config.Server.TLSConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "server failed: %v\n", err)
+		os.Exit(1)
+	}
 }
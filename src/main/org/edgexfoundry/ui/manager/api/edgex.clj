@@ -127,12 +127,24 @@
   (http/delete (str "http://" (get @endpoints target ) "/api/v1/" path))
   nil)
 
-(defn edgex-put-command [url values]
-  (let [conv-val (fn [[name kind value]]
+(defn edgex-put-command [device command values]
+  (let [device-id (key-to-string device)
+        command-id (key-to-string command)
+        cmd (->> (edgex-get-path :command (str "device/" device-id))
+                 walk/keywordize-keys
+                 :commands
+                 (filter #(= (:id %) command-id))
+                 first)
+        allowed (set (-> cmd :put :parameterNames))
+        conv-val (fn [[name kind value]]
                    [name value])
         data (into {} (map conv-val values))]
-    (timbre/info "edgex-put-command" url data)
-    (http/put url {:body (json/write-str data)})
+    (when-not cmd
+      (throw (ex-info (str "Unknown command " command-id) {})))
+    (when-let [unknown (seq (remove allowed (keys data)))]
+      (throw (ex-info (str "Command " (:name cmd) " has no parameter " (first unknown)) {})))
+    (timbre/info "edgex-put-command" device-id command-id data)
+    (edgex-put :command (str "device/" device-id "/command/" command-id) data)
     nil))
 
 (defn edgex-get-endpoints []
@@ -163,9 +163,9 @@
                (e/edgex-delete :metadata))))
 
 (defmutation issue-set-command
-  [{:keys [url values]}]
+  [{:keys [device command values]}]
   (action [{:keys [state] :as env}]
-          (e/edgex-put-command url values)))
+          (e/edgex-put-command device command values)))
 
 (defmutation add-notification
   [{:keys [tempid slug description sender category severity content labels]}]